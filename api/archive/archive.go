@@ -0,0 +1,368 @@
+// Package archive exports the work, skillsTools and projects of the table, and
+// optionally their media, as a single versioned zip archive, and imports such
+// an archive back into a table and bucket.
+//
+// An archive contains:
+//
+//	manifest.json             the Manifest
+//	items/<partition>.json    the items of each partition, e.g. items/Work.json
+//	media/<key>               the object at key, for each media file in the manifest
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+	"github.com/thomasmendez/personal-website-backend/api/reconcile"
+)
+
+// Version is the version of the archive format Export writes and Import reads
+const Version = 1
+
+const (
+	manifestName = "manifest.json"
+	itemsDir     = "items/"
+	mediaDir     = "media/"
+)
+
+// maxJSONSize is the largest manifest or items file Read decodes. DynamoDB
+// items are at most 400KB, so it leaves room for more items than a portfolio has.
+const maxJSONSize int64 = 32 << 20
+
+var (
+	// ErrInvalidArchive is returned by Read for archives that are not zip
+	// files, or whose manifest, items or media are missing or invalid, and by
+	// Import for media whose content is not the manifest's content type
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrUnsupportedVersion is returned by Read for archives of another version
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+)
+
+// Table is the DynamoDB table items are exported from and imported into.
+// The DynamoDB client implements it.
+type Table interface {
+	dynamodb.QueryAPIClient
+	database.BatchWriteItemAPI
+}
+
+// MediaStore is the bucket media is exported from and imported into.
+// *bucket.Bucket implements it.
+type MediaStore interface {
+	GetFile(ctx context.Context, key string) ([]byte, error)
+	GetFileInfo(ctx context.Context, key string) (*s3.HeadObjectOutput, error)
+	SendFileToS3(ctx context.Context, key string, file models.FileData) (*models.MediaRef, error)
+}
+
+type Manifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// Items is the number of items of each partition
+	Items map[string]int `json:"items"`
+	// Media are the media files in the archive, empty unless it was exported
+	// with media
+	Media []MediaFile `json:"media"`
+	// MissingMedia are the media of items that did not exist when exported
+	MissingMedia []reconcile.MissingMedia `json:"missingMedia"`
+}
+
+// MediaFile is an object in the bucket, stored in the archive at media/<key>
+type MediaFile struct {
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// ItemKey is the key of an item in the table
+type ItemKey struct {
+	PersonalWebsiteType string `json:"personalWebsiteType"`
+	SortValue           string `json:"sortValue"`
+}
+
+// Archive is an archive opened by Read
+type Archive struct {
+	Manifest Manifest
+	// Items are the items of each partition
+	Items map[string][]Item
+
+	media map[string]*zip.File
+}
+
+// Read opens the archive in r and validates its manifest and items. Every
+// item must be in the partition of its file, unmarshal into its model, have a
+// sortValue no other item of its partition has and only reference media under
+// the media prefixes, and every media file in the manifest must be in the
+// archive. Nothing is written before the whole archive is validated.
+func Read(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	a := &Archive{
+		Items: make(map[string][]Item),
+		media: make(map[string]*zip.File),
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, file := range zr.File {
+		files[file.Name] = file
+	}
+
+	manifestFile, ok := files[manifestName]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s", ErrInvalidArchive, manifestName)
+	}
+	if err := readJSON(manifestFile, &a.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, manifestName, err)
+	}
+	if a.Manifest.Version != Version {
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrUnsupportedVersion, a.Manifest.Version, Version)
+	}
+
+	for _, partitionKey := range database.PartitionKeys {
+		file, ok := files[itemsDir+partitionKey+".json"]
+		if !ok {
+			continue
+		}
+		var items []Item
+		if err := readJSON(file, &items); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, file.Name, err)
+		}
+		sortValues := make(map[string]bool, len(items))
+		for i, item := range items {
+			key, err := item.key()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s item %d: %v", ErrInvalidArchive, file.Name, i, err)
+			}
+			if key.PersonalWebsiteType != partitionKey {
+				return nil, fmt.Errorf("%w: %s item %s is in partition %s", ErrInvalidArchive, file.Name, key.SortValue, key.PersonalWebsiteType)
+			}
+			// a batch write fails on duplicate keys after earlier batches are written
+			if sortValues[key.SortValue] {
+				return nil, fmt.Errorf("%w: %s has more than one item %s", ErrInvalidArchive, file.Name, key.SortValue)
+			}
+			sortValues[key.SortValue] = true
+			mediaKeys, err := itemMediaKeys(partitionKey, item)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s item %s: %v", ErrInvalidArchive, file.Name, key.SortValue, err)
+			}
+			for _, mediaKey := range mediaKeys {
+				if !validKey(mediaKey) || !isMediaKey(mediaKey) {
+					return nil, fmt.Errorf("%w: %s item %s references media %q that is not under %v", ErrInvalidArchive, file.Name, key.SortValue, mediaKey, bucket.MediaPrefixes)
+				}
+			}
+		}
+		a.Items[partitionKey] = items
+	}
+
+	for _, media := range a.Manifest.Media {
+		if !validKey(media.Key) {
+			return nil, fmt.Errorf("%w: invalid media key %q", ErrInvalidArchive, media.Key)
+		}
+		if !isMediaKey(media.Key) {
+			return nil, fmt.Errorf("%w: media key %q is not under %v", ErrInvalidArchive, media.Key, bucket.MediaPrefixes)
+		}
+		file, ok := files[mediaDir+media.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: no media file for %s", ErrInvalidArchive, media.Key)
+		}
+		if media.Size < 0 || file.UncompressedSize64 != uint64(media.Size) {
+			return nil, fmt.Errorf("%w: media file for %s is %d bytes, not %d", ErrInvalidArchive, media.Key, file.UncompressedSize64, media.Size)
+		}
+		a.media[media.Key] = file
+	}
+
+	return a, nil
+}
+
+// Export writes an archive of every item in the table to w. With media, the
+// objects items reference are included, and media that does not exist is
+// listed in the manifest's MissingMedia.
+func Export(ctx context.Context, table Table, media MediaStore, tableName string, w io.Writer, withMedia bool) (Manifest, error) {
+	manifest := Manifest{
+		Version:      Version,
+		ExportedAt:   time.Now().UTC().Truncate(time.Second),
+		Items:        make(map[string]int),
+		Media:        make([]MediaFile, 0),
+		MissingMedia: make([]reconcile.MissingMedia, 0),
+	}
+
+	items := make(map[string][]Item)
+	var mediaKeys []string
+	seen := make(map[string]bool)
+	for _, partitionKey := range database.PartitionKeys {
+		queried, err := database.QueryItems(ctx, table, tableName, partitionKey)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to get %s: %w", partitionKey, err)
+		}
+		for _, attributes := range queried {
+			item := Item(attributes)
+			items[partitionKey] = append(items[partitionKey], item)
+
+			keys, err := itemMediaKeys(partitionKey, item)
+			if err != nil {
+				return manifest, fmt.Errorf("failed to read %s item: %w", partitionKey, err)
+			}
+			for _, key := range keys {
+				if !withMedia || seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				info, err := media.GetFileInfo(ctx, key)
+				if err != nil {
+					return manifest, err
+				}
+				if info == nil {
+					itemKey, _ := item.key()
+					manifest.MissingMedia = append(manifest.MissingMedia, reconcile.MissingMedia{
+						SortValue: itemKey.SortValue,
+						Key:       key,
+					})
+					continue
+				}
+				mediaKeys = append(mediaKeys, key)
+				manifest.Media = append(manifest.Media, MediaFile{
+					Key:         key,
+					ContentType: aws.ToString(info.ContentType),
+					Filename:    dispositionFilename(aws.ToString(info.ContentDisposition)),
+					Size:        aws.ToInt64(info.ContentLength),
+				})
+			}
+		}
+		manifest.Items[partitionKey] = len(items[partitionKey])
+	}
+	sort.Slice(manifest.Media, func(i, j int) bool { return manifest.Media[i].Key < manifest.Media[j].Key })
+	sort.Strings(mediaKeys)
+
+	zw := zip.NewWriter(w)
+	if err := writeJSON(zw, manifestName, manifest.ExportedAt, manifest); err != nil {
+		return manifest, err
+	}
+	for _, partitionKey := range database.PartitionKeys {
+		partitionItems := items[partitionKey]
+		if partitionItems == nil {
+			partitionItems = make([]Item, 0)
+		}
+		if err := writeJSON(zw, itemsDir+partitionKey+".json", manifest.ExportedAt, partitionItems); err != nil {
+			return manifest, err
+		}
+	}
+	for _, key := range mediaKeys {
+		content, err := media.GetFile(ctx, key)
+		if err != nil {
+			return manifest, err
+		}
+		if content == nil {
+			return manifest, fmt.Errorf("media %s was deleted during the export", key)
+		}
+		file, err := zw.CreateHeader(&zip.FileHeader{Name: mediaDir + key, Method: zip.Store, Modified: manifest.ExportedAt})
+		if err != nil {
+			return manifest, err
+		}
+		if _, err := file.Write(content); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, zw.Close()
+}
+
+// itemMediaKeys unmarshals item into the model of its partition and returns
+// the S3 keys of its media, the company logo of work and the media of projects
+func itemMediaKeys(partitionKey string, item Item) ([]string, error) {
+	switch partitionKey {
+	case "Work":
+		var work models.Work
+		if err := attributevalue.UnmarshalMap(item, &work); err != nil {
+			return nil, err
+		}
+		if ref := work.GetCompanyLogoRef(); ref.IsS3() {
+			return []string{ref.Key}, nil
+		}
+	case "SkillsTools":
+		var skillsTools models.SkillsTools
+		if err := attributevalue.UnmarshalMap(item, &skillsTools); err != nil {
+			return nil, err
+		}
+	case "Projects":
+		var project models.Project
+		if err := attributevalue.UnmarshalMap(item, &project); err != nil {
+			return nil, err
+		}
+		return project.MediaFileNames(), nil
+	}
+	return nil, nil
+}
+
+func readJSON(file *zip.File, v any) error {
+	content, err := readFile(file, maxJSONSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// readFile returns the content of file, which must be at most limit bytes.
+// The size in the zip header is checked before the file is decompressed, and
+// the content is read up to the limit since the header can be forged.
+func readFile(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is %d bytes, over the limit of %d bytes", file.Name, file.UncompressedSize64, limit)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%s is over the limit of %d bytes", file.Name, limit)
+	}
+	return content, nil
+}
+
+func writeJSON(zw *zip.Writer, name string, modified time.Time, v any) error {
+	file, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// validKey reports whether key is a clean relative S3 key
+func validKey(key string) bool {
+	return key != "" && path.Clean(key) == key && !strings.HasPrefix(key, "/") && !strings.HasPrefix(key, "../")
+}
+
+// isMediaKey reports whether key is under one of the prefixes media is
+// uploaded to, so an import cannot overwrite other objects such as resume
+// templates
+func isMediaKey(key string) bool {
+	for _, prefix := range bucket.MediaPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
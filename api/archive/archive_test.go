@@ -0,0 +1,505 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// fakeTable stores items by personalWebsiteType and sortValue
+type fakeTable struct {
+	items  map[ItemKey]Item
+	writes int
+}
+
+func (f *fakeTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	partitionKey := params.ExpressionAttributeValues[":partitionKey"].(*types.AttributeValueMemberS).Value
+	var keys []ItemKey
+	for key := range f.items {
+		if key.PersonalWebsiteType == partitionKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].SortValue < keys[j].SortValue })
+
+	output := &dynamodb.QueryOutput{}
+	for _, key := range keys {
+		output.Items = append(output.Items, f.items[key])
+	}
+	return output, nil
+}
+
+func (f *fakeTable) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	for _, requests := range params.RequestItems {
+		for _, request := range requests {
+			item := make(Item, len(request.PutRequest.Item))
+			for name, value := range request.PutRequest.Item {
+				item[name] = value
+			}
+			key, _ := item.key()
+			f.items[key] = item
+			f.writes++
+		}
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+type fakeObject struct {
+	content     []byte
+	contentType string
+	disposition string
+}
+
+type fakeMediaStore map[string]fakeObject
+
+func (f fakeMediaStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return f[key].content, nil
+}
+
+func (f fakeMediaStore) GetFileInfo(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	object, ok := f[key]
+	if !ok {
+		return nil, nil
+	}
+	return &s3.HeadObjectOutput{
+		ContentType:        aws.String(object.contentType),
+		ContentDisposition: aws.String(object.disposition),
+		ContentLength:      aws.Int64(int64(len(object.content))),
+	}, nil
+}
+
+func (f fakeMediaStore) SendFileToS3(ctx context.Context, key string, file models.FileData) (*models.MediaRef, error) {
+	f[key] = fakeObject{content: file.Content, contentType: file.ContentType, disposition: "inline; filename=" + file.Filename}
+	return &models.MediaRef{Storage: models.StorageS3, Key: key}, nil
+}
+
+func s3Ref(key string) types.AttributeValue {
+	return &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"storage": &types.AttributeValueMemberS{Value: models.StorageS3},
+		"key":     &types.AttributeValueMemberS{Value: key},
+	}}
+}
+
+func testItems() map[ItemKey]Item {
+	items := []Item{
+		{
+			"personalWebsiteType": &types.AttributeValueMemberS{Value: "Work"},
+			"sortValue":           &types.AttributeValueMemberS{Value: "2020-01-01"},
+			"jobTitle":            &types.AttributeValueMemberS{Value: "Software Engineer"},
+			"companyLogoRef":      s3Ref("work/logo.png"),
+			"jobDescription": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "Developed backend systems"},
+			}},
+		},
+		{
+			"personalWebsiteType": &types.AttributeValueMemberS{Value: "SkillsTools"},
+			"sortValue":           &types.AttributeValueMemberS{Value: "Software Engineering"},
+			"categories":          &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		},
+		{
+			"personalWebsiteType": &types.AttributeValueMemberS{Value: "Projects"},
+			"sortValue":           &types.AttributeValueMemberS{Value: "Personal Website"},
+			"name":                &types.AttributeValueMemberS{Value: "Personal Website"},
+			"mediaRef":            s3Ref("projects/screenshot.png"),
+			"media": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"ref": s3Ref("projects/missing.png")}},
+			}},
+		},
+	}
+	byKey := make(map[ItemKey]Item)
+	for _, item := range items {
+		key, _ := item.key()
+		byKey[key] = item
+	}
+	return byKey
+}
+
+func TestItemJSON(t *testing.T) {
+	item := Item{
+		"s":    &types.AttributeValueMemberS{Value: "text"},
+		"n":    &types.AttributeValueMemberN{Value: "42"},
+		"b":    &types.AttributeValueMemberB{Value: []byte{0, 1}},
+		"bool": &types.AttributeValueMemberBOOL{Value: true},
+		"null": &types.AttributeValueMemberNULL{Value: true},
+		"ss":   &types.AttributeValueMemberSS{Value: []string{"b", "a"}},
+		"ns":   &types.AttributeValueMemberNS{Value: []string{"2", "1"}},
+		"bs":   &types.AttributeValueMemberBS{Value: [][]byte{{1}}},
+		"l":    &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "x"}}},
+		"m":    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"k": &types.AttributeValueMemberN{Value: "1"}}},
+	}
+
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"s":{"S":"text"}`, `"n":{"N":"42"}`, `"ss":{"SS":["a","b"]}`, `"m":{"M":{"k":{"N":"1"}}}`, `"null":{"NULL":true}`} {
+		if !strings.Contains(string(encoded), expected) {
+			t.Errorf("expected %s to contain %s", encoded, expected)
+		}
+	}
+
+	var decoded Item
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := changedAttributes(item, decoded); err != nil || len(changed) > 0 {
+		t.Errorf("expected the item to round trip, changed %v (%v)", changed, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"s":{"X":"text"}}`), &decoded); err == nil {
+		t.Errorf("expected an error for an unknown type")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeTable{items: testItems()}
+	sourceMedia := fakeMediaStore{
+		"work/logo.png":           {content: []byte(pngSignature + "logo"), contentType: "image/png", disposition: `inline; filename="logo.png"`},
+		"projects/screenshot.png": {content: []byte(pngSignature + "screenshot"), contentType: "image/png"},
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Export(ctx, source, sourceMedia, "table", &buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(manifest.Items, map[string]int{"Work": 1, "SkillsTools": 1, "Projects": 1}) {
+		t.Errorf("unexpected item counts %v", manifest.Items)
+	}
+	if len(manifest.Media) != 2 || manifest.Media[1].Key != "work/logo.png" || manifest.Media[1].Filename != "logo.png" {
+		t.Errorf("unexpected media %+v", manifest.Media)
+	}
+	if len(manifest.MissingMedia) != 1 || manifest.MissingMedia[0].Key != "projects/missing.png" {
+		t.Errorf("unexpected missing media %+v", manifest.MissingMedia)
+	}
+
+	a, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := &fakeTable{items: make(map[ItemKey]Item)}
+	targetMedia := fakeMediaStore{"projects/screenshot.png": sourceMedia["projects/screenshot.png"]}
+
+	report, err := Import(ctx, target, targetMedia, "table", a, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added) != 3 || target.writes != 0 || len(targetMedia) != 1 {
+		t.Errorf("expected a dry run to write nothing, got %+v with %d writes", report, target.writes)
+	}
+	if !reflect.DeepEqual(report.UploadedMedia, []string{"work/logo.png"}) || report.ExistingMedia != 1 {
+		t.Errorf("unexpected media report %+v", report)
+	}
+
+	if _, err := Import(ctx, target, targetMedia, "table", a, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(target.items, source.items) {
+		t.Errorf("expected the imported items to equal the exported items")
+	}
+	if string(targetMedia["work/logo.png"].content) != pngSignature+"logo" || targetMedia["work/logo.png"].disposition != "inline; filename=logo.png" {
+		t.Errorf("unexpected uploaded logo %+v", targetMedia["work/logo.png"])
+	}
+
+	workKey := ItemKey{PersonalWebsiteType: "Work", SortValue: "2020-01-01"}
+	target.items[workKey]["jobTitle"] = &types.AttributeValueMemberS{Value: "Engineer"}
+	extraKey := ItemKey{PersonalWebsiteType: "Projects", SortValue: "Other"}
+	target.items[extraKey] = Item{
+		"personalWebsiteType": &types.AttributeValueMemberS{Value: "Projects"},
+		"sortValue":           &types.AttributeValueMemberS{Value: "Other"},
+	}
+	target.writes = 0
+
+	report, err = Import(ctx, target, targetMedia, "table", a, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedChanges := []ItemChange{{ItemKey: workKey, Attributes: []string{"jobTitle"}}}
+	if !reflect.DeepEqual(report.Changed, expectedChanges) || report.Unchanged != 2 || len(report.Added) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !reflect.DeepEqual(report.NotInArchive, []ItemKey{extraKey}) {
+		t.Errorf("expected %v not in the archive, got %v", extraKey, report.NotInArchive)
+	}
+	if target.writes != 1 {
+		t.Errorf("expected only the changed item to be written, got %d writes", target.writes)
+	}
+}
+
+// pngSignature starts the content of PNG media, which is all imports check
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// zipArchive returns a zip file of files by name
+func zipArchive(files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	manifest := `{"version": 1}`
+	work := `[{"personalWebsiteType": {"S": "Work"}, "sortValue": {"S": "2020-01-01"}}]`
+
+	for _, test := range []struct {
+		label       string
+		content     []byte
+		expectError error
+	}{
+		{
+			label:   "Valid",
+			content: zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": work}),
+		},
+		{
+			label:       "Not a zip file",
+			content:     []byte("manifest"),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "No manifest",
+			content:     zipArchive(map[string]string{"items/Work.json": work}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Other version",
+			content:     zipArchive(map[string]string{"manifest.json": `{"version": 2}`}),
+			expectError: ErrUnsupportedVersion,
+		},
+		{
+			label:       "Item in another partition",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Projects.json": work}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Item without a sortValue",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": `[{"personalWebsiteType": {"S": "Work"}}]`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Item that is not a model",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": `[{"personalWebsiteType": {"S": "Work"}, "sortValue": {"S": "2020"}, "jobDescription": {"N": "1"}}]`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Missing media file",
+			content:     zipArchive(map[string]string{"manifest.json": `{"version": 1, "media": [{"key": "work/logo.png"}]}`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Media key outside the media prefixes",
+			content:     zipArchive(map[string]string{"manifest.json": `{"version": 1, "media": [{"key": "templates/resume/default.html.tmpl"}]}`, "media/templates/resume/default.html.tmpl": "{{.}}"}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Media file of another size than the manifest",
+			content:     zipArchive(map[string]string{"manifest.json": `{"version": 1, "media": [{"key": "work/logo.png", "size": 2}]}`, "media/work/logo.png": "logo"}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Items file over the limit",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": work + strings.Repeat(" ", int(maxJSONSize))}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Duplicate sortValue",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": `[{"personalWebsiteType": {"S": "Work"}, "sortValue": {"S": "2020-01-01"}}, {"personalWebsiteType": {"S": "Work"}, "sortValue": {"S": "2020-01-01"}}]`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Item media outside the media prefixes",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Work.json": `[{"personalWebsiteType": {"S": "Work"}, "sortValue": {"S": "2020-01-01"}, "companyLogoRef": {"M": {"storage": {"S": "s3"}, "key": {"S": "templates/resume/default.html.tmpl"}}}}]`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Item media outside the bucket",
+			content:     zipArchive(map[string]string{"manifest.json": manifest, "items/Projects.json": `[{"personalWebsiteType": {"S": "Projects"}, "sortValue": {"S": "Personal Website"}, "mediaRef": {"M": {"storage": {"S": "s3"}, "key": {"S": "projects/../templates/resume/default.html.tmpl"}}}}]`}),
+			expectError: ErrInvalidArchive,
+		},
+		{
+			label:       "Media key outside the bucket",
+			content:     zipArchive(map[string]string{"manifest.json": `{"version": 1, "media": [{"key": "../logo.png"}]}`, "media/../logo.png": "logo"}),
+			expectError: ErrInvalidArchive,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(test.content), int64(len(test.content)))
+			if test.expectError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.expectError != nil && !errors.Is(err, test.expectError) {
+				t.Errorf("expected %v, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+// forgedZip returns a zip file with a deflated media file for key whose
+// header and manifest claim that it is size bytes
+func forgedZip(t *testing.T, key string, content []byte, size int64) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	fw.Write(content)
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	manifest, _ := json.Marshal(Manifest{Version: Version, Media: []MediaFile{{Key: key, ContentType: "image/png", Size: size}}})
+	w, _ := zw.Create(manifestName)
+	w.Write(manifest)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               mediaDir + key,
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: uint64(size),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestImportMediaLimits(t *testing.T) {
+	const key = "projects/screenshot.png"
+	bomb := append([]byte(pngSignature), make([]byte, 1<<20)...)
+
+	for _, test := range []struct {
+		label   string
+		content []byte
+	}{
+		{
+			label:   "Over MaxMediaSize",
+			content: forgedZip(t, key, bomb, int64(len(bomb))),
+		},
+		{
+			label:   "Larger than its header",
+			content: forgedZip(t, key, bomb, 1024),
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			a, err := Read(bytes.NewReader(test.content), int64(len(test.content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			targetMedia := fakeMediaStore{}
+			_, err = Import(context.Background(), &fakeTable{items: make(map[ItemKey]Item)}, targetMedia, "table", a, ImportOptions{MaxMediaSize: 64 << 10})
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Errorf("expected %v, got %v", ErrInvalidArchive, err)
+			}
+			if len(targetMedia) != 0 {
+				t.Errorf("expected nothing to be uploaded, got %d objects", len(targetMedia))
+			}
+		})
+	}
+}
+
+func TestImportMedia(t *testing.T) {
+	for _, test := range []struct {
+		label   string
+		key     string
+		content string
+		// contentType is the content type of the media in the manifest
+		contentType         string
+		allowedContentTypes []string
+		expectedContent     string
+		expectError         bool
+	}{
+		{
+			label:           "Detected type",
+			key:             "projects/screenshot.png",
+			content:         pngSignature + "screenshot",
+			contentType:     "image/png",
+			expectedContent: pngSignature + "screenshot",
+		},
+		{
+			label:           "SVG is sanitized",
+			key:             "projects/diagram.svg",
+			content:         `<svg><script>alert(1)</script></svg>`,
+			contentType:     "image/svg+xml",
+			expectedContent: "<svg></svg>",
+		},
+		{
+			label:       "Content is not the manifest's type",
+			key:         "projects/screenshot.jpg",
+			content:     pngSignature + "screenshot",
+			contentType: "image/jpeg",
+			expectError: true,
+		},
+		{
+			label:       "Content is not detectable",
+			key:         "projects/page.html",
+			content:     "<html><script>alert(1)</script></html>",
+			contentType: "text/html",
+			expectError: true,
+		},
+		{
+			label:               "Content type is not allowed",
+			key:                 "projects/screenshot.png",
+			content:             pngSignature + "screenshot",
+			contentType:         "image/png",
+			allowedContentTypes: []string{"application/pdf"},
+			expectError:         true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			manifest, _ := json.Marshal(Manifest{Version: Version, Media: []MediaFile{{Key: test.key, ContentType: test.contentType, Size: int64(len(test.content))}}})
+			content := zipArchive(map[string]string{"manifest.json": string(manifest), mediaDir + test.key: test.content})
+			a, err := Read(bytes.NewReader(content), int64(len(content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			target := &fakeTable{items: make(map[ItemKey]Item)}
+			targetMedia := fakeMediaStore{}
+			for _, dryRun := range []bool{true, false} {
+				_, err = Import(ctx, target, targetMedia, "table", a, ImportOptions{DryRun: dryRun, AllowedContentTypes: test.allowedContentTypes})
+				if test.expectError {
+					if !errors.Is(err, ErrInvalidArchive) || !errors.Is(err, mediatype.ErrUnsupported) {
+						t.Errorf("expected an unsupported media error, got %v", err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			object, ok := targetMedia[test.key]
+			if test.expectError {
+				if ok {
+					t.Errorf("expected %s not to be uploaded", test.key)
+				}
+				return
+			}
+			if string(object.content) != test.expectedContent || object.contentType != test.contentType {
+				t.Errorf("expected %s %q, got %s %q", test.contentType, test.expectedContent, object.contentType, object.content)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+type ImportOptions struct {
+	// DryRun reports the differences between the archive and the table
+	// without writing items or uploading media
+	DryRun bool
+	// AllowedContentTypes are the content types media can have, every
+	// detectable type when nil
+	AllowedContentTypes []string
+	// MaxMediaSize is the largest media file in bytes, DefaultMaxMediaSize
+	// when 0
+	MaxMediaSize int64
+}
+
+// DefaultMaxMediaSize is the largest media file imported when
+// ImportOptions.MaxMediaSize is not set
+const DefaultMaxMediaSize int64 = 100 << 20
+
+type Report struct {
+	Version int  `json:"version"`
+	DryRun  bool `json:"dryRun"`
+	// Added are the items of the archive that are not in the table
+	Added []ItemKey `json:"added"`
+	// Changed are the items of the archive that differ from the table
+	Changed []ItemChange `json:"changed"`
+	// Unchanged is the number of items that are the same in the table
+	Unchanged int `json:"unchanged"`
+	// NotInArchive are the items in the table that are not in the archive.
+	// Imports never delete them.
+	NotInArchive []ItemKey `json:"notInArchive"`
+	// UploadedMedia are the media files that are not in the bucket
+	UploadedMedia []string `json:"uploadedMedia"`
+	// ExistingMedia is the number of media files already in the bucket
+	ExistingMedia int `json:"existingMedia"`
+}
+
+// ItemChange is an item of the archive that differs from the table
+type ItemChange struct {
+	ItemKey
+	// Attributes are the names of the attributes that differ
+	Attributes []string `json:"attributes"`
+}
+
+// Import writes the items of the archive that were added or changed since it
+// was exported, and uploads its media files that are not in the bucket. Media
+// is uploaded before items so imported items never reference missing media.
+// Items are written with BatchWriteItem, and unchanged items are skipped, so
+// an import can be run again, e.g. after a failure.
+//
+// Media is identified by its content as uploads are, and nothing is imported
+// unless every file is an allowed type and the content type in the manifest.
+// SVGs are sanitized before they are uploaded.
+func Import(ctx context.Context, table Table, media MediaStore, tableName string, a *Archive, opts ImportOptions) (Report, error) {
+	report := Report{
+		Version:       a.Manifest.Version,
+		DryRun:        opts.DryRun,
+		Added:         make([]ItemKey, 0),
+		Changed:       make([]ItemChange, 0),
+		NotInArchive:  make([]ItemKey, 0),
+		UploadedMedia: make([]string, 0),
+	}
+
+	allowed := opts.AllowedContentTypes
+	if allowed == nil {
+		allowed = mediatype.Detectable
+	}
+	maxSize := opts.MaxMediaSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxMediaSize
+	}
+	for _, file := range a.Manifest.Media {
+		if _, err := a.prepareMedia(file, allowed, maxSize); err != nil {
+			return report, err
+		}
+	}
+
+	var writes []map[string]types.AttributeValue
+	for _, partitionKey := range database.PartitionKeys {
+		queried, err := database.QueryItems(ctx, table, tableName, partitionKey)
+		if err != nil {
+			return report, fmt.Errorf("failed to get %s: %w", partitionKey, err)
+		}
+		current := make(map[string]Item, len(queried))
+		for _, attributes := range queried {
+			key, err := Item(attributes).key()
+			if err != nil {
+				return report, err
+			}
+			current[key.SortValue] = attributes
+		}
+
+		imported := make(map[string]bool)
+		for _, item := range a.Items[partitionKey] {
+			key, _ := item.key()
+			imported[key.SortValue] = true
+
+			existing, ok := current[key.SortValue]
+			if !ok {
+				report.Added = append(report.Added, key)
+				writes = append(writes, item)
+				continue
+			}
+			attributes, err := changedAttributes(existing, item)
+			if err != nil {
+				return report, err
+			}
+			if len(attributes) == 0 {
+				report.Unchanged++
+				continue
+			}
+			report.Changed = append(report.Changed, ItemChange{ItemKey: key, Attributes: attributes})
+			writes = append(writes, item)
+		}
+
+		for sortValue := range current {
+			if !imported[sortValue] {
+				report.NotInArchive = append(report.NotInArchive, ItemKey{PersonalWebsiteType: partitionKey, SortValue: sortValue})
+			}
+		}
+	}
+	sort.Slice(report.NotInArchive, func(i, j int) bool {
+		if report.NotInArchive[i].PersonalWebsiteType != report.NotInArchive[j].PersonalWebsiteType {
+			return report.NotInArchive[i].PersonalWebsiteType < report.NotInArchive[j].PersonalWebsiteType
+		}
+		return report.NotInArchive[i].SortValue < report.NotInArchive[j].SortValue
+	})
+
+	for _, file := range a.Manifest.Media {
+		info, err := media.GetFileInfo(ctx, file.Key)
+		if err != nil {
+			return report, err
+		}
+		if info != nil {
+			report.ExistingMedia++
+			continue
+		}
+		report.UploadedMedia = append(report.UploadedMedia, file.Key)
+		if opts.DryRun {
+			continue
+		}
+
+		prepared, err := a.prepareMedia(file, allowed, maxSize)
+		if err != nil {
+			return report, err
+		}
+		if _, err := media.SendFileToS3(ctx, file.Key, prepared); err != nil {
+			return report, err
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := database.BatchPutItems(ctx, table, tableName, writes); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// prepareMedia reads the media file, which must be at most maxSize bytes, and
+// checks its content as uploads are. Files that cannot be read return errors
+// wrapping ErrInvalidArchive, and files that are not an allowed type, or not
+// the manifest's content type, wrap mediatype.ErrUnsupported as well.
+func (a *Archive) prepareMedia(file MediaFile, allowed []string, maxSize int64) (models.FileData, error) {
+	content, err := readFile(a.media[file.Key], maxSize)
+	if err != nil {
+		return models.FileData{}, fmt.Errorf("%w: media %s: %v", ErrInvalidArchive, file.Key, err)
+	}
+	contentType, content, err := mediatype.Prepare(content, allowed)
+	if err != nil {
+		return models.FileData{}, fmt.Errorf("%w: media %s: %w", ErrInvalidArchive, file.Key, err)
+	}
+	if contentType != file.ContentType {
+		return models.FileData{}, fmt.Errorf("%w: media %s is %s, not %s: %w", ErrInvalidArchive, file.Key, contentType, file.ContentType, mediatype.ErrUnsupported)
+	}
+
+	filename := file.Filename
+	if filename == "" {
+		filename = path.Base(file.Key)
+	}
+	return models.FileData{
+		Filename:    filename,
+		Content:     content,
+		ContentType: contentType,
+	}, nil
+}
+
+// dispositionFilename returns the filename of a Content-Disposition, or ""
+func dispositionFilename(disposition string) string {
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
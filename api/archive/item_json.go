@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item is a DynamoDB item. Archives store items in the DynamoDB JSON format of
+// the AWS CLI, e.g. {"sortValue": {"S": "2020-01-01"}}, the same format as the
+// payloads in the json directory, so every attribute keeps its type.
+type Item map[string]types.AttributeValue
+
+// MarshalJSON encodes the item in the DynamoDB JSON format. Sets are sorted so
+// the same item always has the same encoding.
+func (item Item) MarshalJSON() ([]byte, error) {
+	object := make(map[string]any, len(item))
+	for name, value := range item {
+		encoded, err := encodeAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		object[name] = encoded
+	}
+	return json.Marshal(object)
+}
+
+// UnmarshalJSON decodes an item in the DynamoDB JSON format
+func (item *Item) UnmarshalJSON(data []byte) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*item = make(Item, len(object))
+	for name, raw := range object {
+		value, err := decodeAttributeValue(raw)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", name, err)
+		}
+		(*item)[name] = value
+	}
+	return nil
+}
+
+// key returns the personalWebsiteType and sortValue of the item
+func (item Item) key() (ItemKey, error) {
+	partitionKey, ok := item["personalWebsiteType"].(*types.AttributeValueMemberS)
+	if !ok {
+		return ItemKey{}, fmt.Errorf("item has no personalWebsiteType string")
+	}
+	sortKey, ok := item["sortValue"].(*types.AttributeValueMemberS)
+	if !ok {
+		return ItemKey{}, fmt.Errorf("item of %s has no sortValue string", partitionKey.Value)
+	}
+	return ItemKey{PersonalWebsiteType: partitionKey.Value, SortValue: sortKey.Value}, nil
+}
+
+func encodeAttributeValue(value types.AttributeValue) (map[string]any, error) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}, nil
+	case *types.AttributeValueMemberB:
+		return map[string]any{"B": v.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]any{"NULL": true}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]any{"SS": sortedStrings(v.Value)}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]any{"NS": sortedStrings(v.Value)}, nil
+	case *types.AttributeValueMemberBS:
+		sets := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			sets[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return map[string]any{"BS": sortedStrings(sets)}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]any, len(v.Value))
+		for i, element := range v.Value {
+			encoded, err := encodeAttributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = encoded
+		}
+		return map[string]any{"L": list}, nil
+	case *types.AttributeValueMemberM:
+		object := make(map[string]any, len(v.Value))
+		for name, element := range v.Value {
+			encoded, err := encodeAttributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = encoded
+		}
+		return map[string]any{"M": object}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute value %T", value)
+}
+
+func decodeAttributeValue(data json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("expected one type, got %d", len(typed))
+	}
+
+	for dataType, raw := range typed {
+		switch dataType {
+		case "S":
+			var s string
+			err := json.Unmarshal(raw, &s)
+			return &types.AttributeValueMemberS{Value: s}, err
+		case "N":
+			var n string
+			err := json.Unmarshal(raw, &n)
+			return &types.AttributeValueMemberN{Value: n}, err
+		case "B":
+			var b []byte
+			err := json.Unmarshal(raw, &b)
+			return &types.AttributeValueMemberB{Value: b}, err
+		case "BOOL":
+			var b bool
+			err := json.Unmarshal(raw, &b)
+			return &types.AttributeValueMemberBOOL{Value: b}, err
+		case "NULL":
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		case "SS":
+			var ss []string
+			err := json.Unmarshal(raw, &ss)
+			return &types.AttributeValueMemberSS{Value: ss}, err
+		case "NS":
+			var ns []string
+			err := json.Unmarshal(raw, &ns)
+			return &types.AttributeValueMemberNS{Value: ns}, err
+		case "BS":
+			var bs [][]byte
+			err := json.Unmarshal(raw, &bs)
+			return &types.AttributeValueMemberBS{Value: bs}, err
+		case "L":
+			var elements []json.RawMessage
+			if err := json.Unmarshal(raw, &elements); err != nil {
+				return nil, err
+			}
+			list := make([]types.AttributeValue, len(elements))
+			for i, element := range elements {
+				value, err := decodeAttributeValue(element)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = value
+			}
+			return &types.AttributeValueMemberL{Value: list}, nil
+		case "M":
+			var elements map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &elements); err != nil {
+				return nil, err
+			}
+			object := make(map[string]types.AttributeValue, len(elements))
+			for name, element := range elements {
+				value, err := decodeAttributeValue(element)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				object[name] = value
+			}
+			return &types.AttributeValueMemberM{Value: object}, nil
+		default:
+			return nil, fmt.Errorf("unknown type %q", dataType)
+		}
+	}
+	return nil, nil
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// changedAttributes returns the names of the attributes that differ between
+// two items, comparing their encodings so the order of sets does not matter
+func changedAttributes(current Item, imported Item) ([]string, error) {
+	var changed []string
+	for name, value := range imported {
+		if equal, err := equalAttributeValues(current[name], value); err != nil {
+			return nil, err
+		} else if !equal {
+			changed = append(changed, name)
+		}
+	}
+	for name := range current {
+		if _, ok := imported[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func equalAttributeValues(a types.AttributeValue, b types.AttributeValue) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	encodedA, err := encodeAttributeValue(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := encodeAttributeValue(b)
+	if err != nil {
+		return false, err
+	}
+	jsonA, err := json.Marshal(encodedA)
+	if err != nil {
+		return false, err
+	}
+	jsonB, err := json.Marshal(encodedB)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(jsonA, jsonB), nil
+}
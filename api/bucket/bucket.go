@@ -1,42 +1,215 @@
 package bucket
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
 	"github.com/thomasmendez/personal-website-backend/api/models"
 )
 
+const (
+	// how long signed URLs are valid for unless URLExpiry is set
+	DefaultURLExpiry = 60 * time.Minute
+	// how long presigned upload URLs are valid for
+	uploadURLExpiry = 15 * time.Minute
+	// keys are content addressed, so an object never changes once uploaded
+	immutableCacheControl = "public, max-age=31536000, immutable"
+)
+
 type Bucket struct {
 	*s3.Client
 	BucketName string
+	// URLSigner signs the URLs media is served from, S3 presigned URLs by default
+	URLSigner URLSigner
+	// URLExpiry is how long signed URLs are valid for
+	URLExpiry time.Duration
+
+	mu            sync.Mutex
+	presignedURLs map[string]presignedURL
 }
 
-func NewBucket(cfg aws.Config, bucketName string) *Bucket {
-	return &Bucket{s3.NewFromConfig(cfg), bucketName}
+type presignedURL struct {
+	url     string
+	expires time.Time
 }
 
-func (b *Bucket) SendFileToS3(ctx context.Context, file models.FileData) (string, error) {
+func NewBucket(cfg aws.Config, bucketName string, options ...func(*s3.Options)) *Bucket {
+	client := s3.NewFromConfig(cfg, options...)
+	return &Bucket{
+		Client:        client,
+		BucketName:    bucketName,
+		URLSigner:     &S3URLSigner{Client: client, BucketName: bucketName},
+		URLExpiry:     DefaultURLExpiry,
+		presignedURLs: make(map[string]presignedURL),
+	}
+}
+
+// SendFileToS3 uploads file to key and returns its reference. Keys are content
+// addressed (see MediaKey), so the upload is skipped when the object exists.
+func (b *Bucket) SendFileToS3(ctx context.Context, key string, file models.FileData) (*models.MediaRef, error) {
+	exists, err := b.FileExistsInS3(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing file in S3: %w", err)
+	}
+	if exists {
+		return b.MediaRef(key, file.ContentType, int64(len(file.Content))), nil
+	}
+	return b.putFile(ctx, key, file)
+}
+
+// putFile uploads file to key, replacing the object if it exists
+func (b *Bucket) putFile(ctx context.Context, key string, file models.FileData) (*models.MediaRef, error) {
 	inputPut := &s3.PutObjectInput{
-		Bucket:      aws.String(b.BucketName),
-		Key:         aws.String(file.Filename),
-		Body:        strings.NewReader(string(file.Content)),
-		ContentType: aws.String(file.ContentType),
+		Bucket:             aws.String(b.BucketName),
+		Key:                aws.String(key),
+		Body:               bytes.NewReader(file.Content),
+		ContentType:        aws.String(file.ContentType),
+		ContentDisposition: aws.String(ContentDisposition(file.Filename)),
+		CacheControl:       aws.String(immutableCacheControl),
 	}
 
 	_, err := b.PutObject(ctx, inputPut)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+		return nil, fmt.Errorf("failed to upload to S3: %w", err)
 	}
+	return b.MediaRef(key, file.ContentType, int64(len(file.Content))), nil
+}
 
-	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", b.BucketName, file.Filename), nil
+// UploadMedia uploads file to key and returns its reference. Images have
+// their metadata stripped and resized variants are uploaded next to them.
+func (b *Bucket) UploadMedia(ctx context.Context, key string, file models.FileData) (*models.MediaRef, []models.MediaVariant, error) {
+	return b.uploadMedia(ctx, key, file, b.SendFileToS3)
+}
+
+// ReplaceMedia is UploadMedia for a file that is already stored at key, such
+// as one uploaded with a presigned URL. The object is replaced with the
+// processed file even though it exists.
+func (b *Bucket) ReplaceMedia(ctx context.Context, key string, file models.FileData) (*models.MediaRef, []models.MediaVariant, error) {
+	return b.uploadMedia(ctx, key, file, b.putFile)
+}
+
+func (b *Bucket) uploadMedia(ctx context.Context, key string, file models.FileData, send func(context.Context, string, models.FileData) (*models.MediaRef, error)) (*models.MediaRef, []models.MediaVariant, error) {
+	if !imaging.IsProcessable(file.ContentType) {
+		ref, err := send(ctx, key, file)
+		return ref, nil, err
+	}
+
+	content, processed, err := imaging.Process(file.Content, file.ContentType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to process image %s: %w", file.Filename, err)
+	}
+	file.Content = content
+
+	ref, err := send(ctx, key, file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variants := make([]models.MediaVariant, 0, len(processed))
+	for _, variant := range processed {
+		variantKey := VariantKey(key, variant.Name, variant.ContentType)
+		variantRef, err := b.SendFileToS3(ctx, variantKey, models.FileData{
+			Filename:    path.Base(variantKey),
+			Content:     variant.Content,
+			ContentType: variant.ContentType,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to upload %s variant of %s: %w", variant.Name, file.Filename, err)
+		}
+		variants = append(variants, models.MediaVariant{
+			Name:        variant.Name,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			ContentType: variant.ContentType,
+			Ref:         variantRef,
+		})
+	}
+	return ref, variants, nil
+}
+
+// LogoContentTypes are the content types a company logo can have
+var LogoContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/avif", "image/svg+xml"}
+
+// UploadCompanyLogo uploads file as the logo of the work with sortValue, with
+// its metadata stripped, and returns its reference. Logos that are not one of
+// LogoContentTypes return an error wrapping mediatype.ErrUnsupported.
+func (b *Bucket) UploadCompanyLogo(ctx context.Context, sortValue string, file models.FileData) (*models.MediaRef, error) {
+	if !slices.Contains(LogoContentTypes, file.ContentType) {
+		return nil, fmt.Errorf("%w: company logo %s must be one of %v", mediatype.ErrUnsupported, file.Filename, LogoContentTypes)
+	}
+	content, err := imaging.StripMetadata(file.Content, file.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip metadata of company logo %s: %w", file.Filename, err)
+	}
+	file.Content = content
+	return b.SendFileToS3(ctx, MediaKey(KeyPrefix(WorkMediaPrefix, sortValue), file), file)
+}
+
+// MediaRef returns the reference of the object at key
+func (b *Bucket) MediaRef(key string, contentType string, size int64) *models.MediaRef {
+	return &models.MediaRef{
+		Storage:     models.StorageS3,
+		Bucket:      b.BucketName,
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+	}
+}
+
+// ParseMediaLink returns the reference of a link, which is a URL of an
+// object in the bucket as returned by the API or stored before references,
+// or an external link. URLs of objects in other buckets are external links,
+// so their keys are never read or deleted in this bucket.
+func (b *Bucket) ParseMediaLink(mediaLink string) *models.MediaRef {
+	if key, ok := b.URLSigner.ObjectKey(mediaLink); ok {
+		return b.MediaRef(key, "", 0)
+	}
+	ref := models.ParseMediaLink(mediaLink)
+	if ref.IsS3() && ref.Bucket != b.BucketName {
+		return &models.MediaRef{Storage: models.StorageURL, URL: mediaLink}
+	}
+	return ref
+}
+
+// ObjectURL returns the unsigned S3 URL of key
+func (b *Bucket) ObjectURL(key string) string {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", b.BucketName, key)
+}
+
+// ContentDisposition returns the Content-Disposition of an object uploaded as
+// filename. Objects are displayed inline, so videos and PDFs play in the
+// browser, and saved under their original filename.
+func ContentDisposition(filename string) string {
+	if filename == "" {
+		return "inline"
+	}
+	return mime.FormatMediaType("inline", map[string]string{"filename": path.Base(filename)})
+}
+
+// CheckBucketAccess checks that the bucket exists and the function has
+// permission to access it
+func (b *Bucket) CheckBucketAccess(ctx context.Context) error {
+	_, err := b.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(b.BucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to access bucket %s: %w", b.BucketName, err)
+	}
+	return nil
 }
 
 func (b *Bucket) FileExistsInS3(ctx context.Context, fileName string) (bool, error) {
@@ -45,9 +218,11 @@ func (b *Bucket) FileExistsInS3(ctx context.Context, fileName string) (bool, err
 		Key:    aws.String(fileName),
 	})
 	if err != nil {
-		// Check if it's a "not found" error
-		var nf *types.NoSuchKey
-		if errors.As(err, &nf) {
+		// Check if it's a "not found" error, HeadObject responses have no body
+		// so S3 reports NotFound rather than NoSuchKey
+		var nf *types.NotFound
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nf) || errors.As(err, &nsk) {
 			return false, nil // File doesn't exist, but no error
 		}
 		return false, err // Some other error occurred
@@ -66,22 +241,146 @@ func (b *Bucket) DeleteFileFromS3(ctx context.Context, fileName string) error {
 		return fmt.Errorf("failed to delete file from S3: %w", err)
 	}
 
+	b.mu.Lock()
+	delete(b.presignedURLs, fileName)
+	b.mu.Unlock()
+
 	return nil
 }
 
-func (b *Bucket) GeneratePresignedURL(ctx context.Context, fileName string) (*v4.PresignedHTTPRequest, error) {
-	inputGet := &s3.GetObjectInput{
-		Bucket: aws.String(b.BucketName),
-		Key:    aws.String(fileName),
+// PresignUpload returns a presigned PUT request for uploading an object of
+// contentType and contentLength to key, and the time it expires. checksum is
+// the base64 encoded SHA-256 of the content, S3 rejects uploads that do not
+// match it. The headers in the request's SignedHeader must be sent with it.
+func (b *Bucket) PresignUpload(ctx context.Context, key string, file models.FileData, contentLength int64, checksum string) (*v4.PresignedHTTPRequest, time.Time, error) {
+	inputPut := &s3.PutObjectInput{
+		Bucket:             aws.String(b.BucketName),
+		Key:                aws.String(key),
+		ContentType:        aws.String(file.ContentType),
+		ContentLength:      aws.Int64(contentLength),
+		ContentDisposition: aws.String(ContentDisposition(file.Filename)),
+		CacheControl:       aws.String(immutableCacheControl),
+		ChecksumSHA256:     aws.String(checksum),
 	}
 
+	expires := time.Now().Add(uploadURLExpiry)
 	presignClient := s3.NewPresignClient(b.Client)
-	presignedReq, err := presignClient.PresignGetObject(ctx, inputGet, func(opts *s3.PresignOptions) {
-		opts.Expires = time.Duration(60 * time.Minute) // URL expires in 1 hour
+	presignedReq, err := presignClient.PresignPutObject(ctx, inputPut, func(opts *s3.PresignOptions) {
+		opts.Expires = uploadURLExpiry
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+
+	return presignedReq, expires, nil
+}
+
+// GetFileInfo returns the metadata of the object at key, or nil if it does not exist
+func (b *Bucket) GetFileInfo(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	output, err := b.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var nf *types.NotFound
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nf) || errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get file info from S3: %w", err)
+	}
+	return output, nil
+}
+
+// GetFile returns the content of the object at key, or nil if it does not exist
+func (b *Bucket) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return b.getFile(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.BucketName),
+		Key:    aws.String(key),
+	})
+}
+
+// GetFileHead returns up to the first n bytes of the object at key, or nil if
+// it does not exist
+func (b *Bucket) GetFileHead(ctx context.Context, key string, n int64) ([]byte, error) {
+	return b.getFile(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.BucketName),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
 	})
+}
+
+func (b *Bucket) getFile(ctx context.Context, input *s3.GetObjectInput) ([]byte, error) {
+	key := aws.ToString(input.Key)
+	output, err := b.GetObject(ctx, input)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get file from S3: %w", err)
+	}
+	defer output.Body.Close()
+
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s from S3: %w", key, err)
+	}
+	return content, nil
+}
+
+// GetPresignedURL returns a signed URL for fileName from the URLSigner and
+// the time it is regenerated. URLs are signed for windows of a sixth of their
+// lifetime and expire URLExpiry after the start of their window, so every
+// instance regenerates its URLs at the same times, and responses that include
+// them stay valid for a while after they are cached. URLs are cached per
+// object and reused within their window.
+func (b *Bucket) GetPresignedURL(ctx context.Context, fileName string) (string, time.Time, error) {
+	window := b.URLExpiry / 6
+	start := time.Now().Truncate(window)
+	expires := start.Add(b.URLExpiry)
+	refresh := start.Add(window)
+
+	b.mu.Lock()
+	cached, ok := b.presignedURLs[fileName]
+	b.mu.Unlock()
+	if ok && cached.expires.Equal(expires) {
+		return cached.url, refresh, nil
+	}
+
+	signedURL, err := b.URLSigner.SignURL(ctx, fileName, expires)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+		return "", time.Time{}, err
+	}
+
+	b.mu.Lock()
+	b.presignedURLs[fileName] = presignedURL{url: signedURL, expires: expires}
+	b.mu.Unlock()
+
+	return signedURL, refresh, nil
+}
+
+// PresignedURLCacheTTL returns how long a response containing a presigned URL
+// regenerated at refresh, as returned by GetPresignedURL, can be cached
+func PresignedURLCacheTTL(refresh time.Time) time.Duration {
+	return max(time.Until(refresh), 0)
+}
+
+// ListFilesInS3 returns every object in the bucket whose key starts with prefix
+func (b *Bucket) ListFilesInS3(ctx context.Context, prefix string) ([]types.Object, error) {
+	var objects []types.Object
+
+	paginator := s3.NewListObjectsV2Paginator(b.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.BucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files in S3: %w", err)
+		}
+		objects = append(objects, page.Contents...)
 	}
 
-	return presignedReq, nil
+	return objects, nil
 }
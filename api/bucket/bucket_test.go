@@ -0,0 +1,125 @@
+package bucket
+
+import (
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestParseMediaLink(t *testing.T) {
+	s3Bucket := &Bucket{
+		BucketName: "my-bucket",
+		URLSigner:  &S3URLSigner{BucketName: "my-bucket"},
+	}
+	cdnBucket := &Bucket{
+		BucketName: "my-bucket",
+		URLSigner:  &CDNURLSigner{BaseURL: "https://cdn.example.com/media"},
+	}
+
+	for _, test := range []struct {
+		label       string
+		bucket      *Bucket
+		mediaLink   string
+		expectedRef *models.MediaRef
+	}{
+		{
+			label:       "Global endpoint",
+			bucket:      s3Bucket,
+			mediaLink:   "https://my-bucket.s3.amazonaws.com/projects/personal-website/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "projects/personal-website/abc.png"},
+		},
+		{
+			label:       "Regional presigned URL",
+			bucket:      s3Bucket,
+			mediaLink:   "https://my-bucket.s3.us-east-2.amazonaws.com/abc.png?X-Amz-Expires=3600",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "abc.png"},
+		},
+		{
+			label:       "Legacy regional endpoint",
+			bucket:      s3Bucket,
+			mediaLink:   "https://my-bucket.s3-us-west-2.amazonaws.com/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "abc.png"},
+		},
+		{
+			label:       "Path style",
+			bucket:      s3Bucket,
+			mediaLink:   "https://s3.eu-west-1.amazonaws.com/my-bucket/old%20screenshot.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "old screenshot.png"},
+		},
+		{
+			label: "Bucket name with dots",
+			bucket: &Bucket{
+				BucketName: "media.s3.example",
+				URLSigner:  &S3URLSigner{BucketName: "media.s3.example"},
+			},
+			mediaLink:   "https://media.s3.example.s3.dualstack.us-east-1.amazonaws.com/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "media.s3.example", Key: "abc.png"},
+		},
+		{
+			label:       "Other bucket",
+			bucket:      s3Bucket,
+			mediaLink:   "https://other.s3.amazonaws.com/projects/personal-website/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://other.s3.amazonaws.com/projects/personal-website/abc.png"},
+		},
+		{
+			label:       "CDN URL",
+			bucket:      cdnBucket,
+			mediaLink:   "https://cdn.example.com/media/projects/personal-website/abc.png?Expires=1767225600",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "projects/personal-website/abc.png"},
+		},
+		{
+			label:       "S3 URL stored before the CDN",
+			bucket:      cdnBucket,
+			mediaLink:   "https://my-bucket.s3.amazonaws.com/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageS3, Bucket: "my-bucket", Key: "abc.png"},
+		},
+		{
+			label:       "Other bucket behind the CDN",
+			bucket:      cdnBucket,
+			mediaLink:   "https://other.s3.amazonaws.com/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://other.s3.amazonaws.com/abc.png"},
+		},
+		{
+			label:       "Other path on the CDN host",
+			bucket:      cdnBucket,
+			mediaLink:   "https://cdn.example.com/other/abc.png",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://cdn.example.com/other/abc.png"},
+		},
+		{
+			label:       "External link",
+			bucket:      s3Bucket,
+			mediaLink:   "https://www.youtube.com/watch?v=abc",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://www.youtube.com/watch?v=abc"},
+		},
+		{
+			label:       "Link mentioning S3",
+			bucket:      s3Bucket,
+			mediaLink:   "https://example.com/?from=s3.amazonaws.com",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://example.com/?from=s3.amazonaws.com"},
+		},
+		{
+			label:       "Bucket without a key",
+			bucket:      s3Bucket,
+			mediaLink:   "https://my-bucket.s3.amazonaws.com/",
+			expectedRef: &models.MediaRef{Storage: models.StorageURL, URL: "https://my-bucket.s3.amazonaws.com/"},
+		},
+		{
+			label:     "Empty link",
+			bucket:    s3Bucket,
+			mediaLink: "",
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			ref := test.bucket.ParseMediaLink(test.mediaLink)
+			if test.expectedRef == nil {
+				if ref != nil {
+					t.Errorf("expected no reference, got %+v", ref)
+				}
+				return
+			}
+			if ref == nil || *ref != *test.expectedRef {
+				t.Errorf("expected %+v, got %+v", test.expectedRef, ref)
+			}
+		})
+	}
+}
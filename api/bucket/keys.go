@@ -0,0 +1,126 @@
+package bucket
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// ResumeTemplatePrefix is the prefix of resume templates uploaded to the
+// bucket, e.g. templates/resume/default.html.tmpl
+const ResumeTemplatePrefix = "templates/resume/"
+
+const (
+	// ProjectMediaPrefix is the first segment of the keys of project media
+	ProjectMediaPrefix = "projects"
+	// WorkMediaPrefix is the first segment of the keys of company logos
+	WorkMediaPrefix = "work"
+)
+
+// MediaPrefixes are the prefixes of every key media is uploaded to. Other
+// objects in the bucket, such as resume templates, are not media.
+var MediaPrefixes = []string{ProjectMediaPrefix + "/", WorkMediaPrefix + "/"}
+
+// file extensions for the content types detected on upload
+var contentTypeExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"image/avif":      ".avif",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"application/pdf": ".pdf",
+}
+
+// MediaKey returns the S3 key for file under prefix. The key is derived from
+// a hash of the file content rather than the client supplied filename, so
+// uploading the same content again resolves to the same object.
+//
+// Example:
+//
+//	MediaKey("projects/personal-website-bff86b0df1a97b87", file) // projects/personal-website-bff86b0df1a97b87/9f86d081884c7d65....png
+func MediaKey(prefix string, file models.FileData) string {
+	hash := sha256.Sum256(file.Content)
+	return ChecksumMediaKey(prefix, hex.EncodeToString(hash[:]), file)
+}
+
+// ChecksumMediaKey returns the same key as MediaKey for a file that is not
+// uploaded through the API, from the hex encoded SHA-256 of its content
+func ChecksumMediaKey(prefix string, checksum string, file models.FileData) string {
+	return path.Join(prefix, strings.ToLower(checksum)+mediaExtension(file))
+}
+
+// MediaItemID derives the ID of a gallery item from the content hash in its key
+func MediaItemID(key string) string {
+	id := strings.TrimSuffix(path.Base(key), path.Ext(key))
+	if len(id) > 16 {
+		id = id[:16]
+	}
+	return id
+}
+
+// KeyPrefix returns the key prefix of the media of the item named name under
+// mediaPrefix. The sanitized name keeps the key readable and a hash of the raw
+// name keeps names that sanitize the same, e.g. "C++ Engine" and "C Engine",
+// from sharing a prefix.
+//
+// Example:
+//
+//	KeyPrefix("projects", "Personal Website") // projects/personal-website-bff86b0df1a97b87
+func KeyPrefix(mediaPrefix string, name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return path.Join(mediaPrefix, sanitizeKeySegment(name)+"-"+hex.EncodeToString(hash[:8]))
+}
+
+// mediaExtension returns the extension for the detected content type, falling
+// back to the sanitized extension of the uploaded filename
+func mediaExtension(file models.FileData) string {
+	if ext, ok := contentTypeExtensions[file.ContentType]; ok {
+		return ext
+	}
+	ext := strings.TrimPrefix(path.Ext(file.Filename), ".")
+	ext = sanitizeKeySegment(ext)
+	if ext == "untitled" || len(ext) > 10 {
+		return ""
+	}
+	return "." + ext
+}
+
+// sanitizeKeySegment lowercases s and replaces anything other than letters,
+// digits, dots and dashes with a dash so it is safe to use in an S3 key
+func sanitizeKeySegment(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.':
+			b.WriteRune(r)
+			dash = false
+		case !dash:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	segment := strings.Trim(b.String(), "-.")
+	if segment == "" {
+		return "untitled"
+	}
+	return segment
+}
+
+// VariantKey returns the S3 key of a resized variant of the object at key.
+// Variants are stored next to the original under a prefix named after it.
+//
+// Example:
+//
+//	VariantKey("projects/personal-website-bff86b0df1a97b87/9f86d081884c7d65....png", "thumb", "image/webp") // projects/personal-website-bff86b0df1a97b87/9f86d081884c7d65.../thumb.webp
+func VariantKey(key string, name string, contentType string) string {
+	base := strings.TrimSuffix(key, path.Ext(key))
+	return path.Join(base, sanitizeKeySegment(name)+contentTypeExtensions[contentType])
+}
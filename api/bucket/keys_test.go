@@ -0,0 +1,70 @@
+package bucket
+
+import (
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestMediaKey(t *testing.T) {
+	// sha256 of "hello"
+	const hash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	for _, test := range []struct {
+		label       string
+		prefix      string
+		file        models.FileData
+		expectedKey string
+	}{
+		{
+			label:       "Extension from content type",
+			prefix:      KeyPrefix("projects", "Personal Website"),
+			file:        models.FileData{Filename: "screenshot.jpeg", Content: []byte("hello"), ContentType: "image/jpeg"},
+			expectedKey: "projects/personal-website-bff86b0df1a97b87/" + hash + ".jpg",
+		},
+		{
+			label:       "Path traversal in filename and sort value",
+			prefix:      KeyPrefix("projects", "../../etc"),
+			file:        models.FileData{Filename: "../../passwd.PNG", Content: []byte("hello"), ContentType: "application/octet-stream"},
+			expectedKey: "projects/etc-74ccf3c5b4c19a81/" + hash + ".png",
+		},
+		{
+			label:       "Spaces and symbols in filename",
+			prefix:      KeyPrefix("projects", "My Project: v2!"),
+			file:        models.FileData{Filename: "my file (1).tar gz", Content: []byte("hello")},
+			expectedKey: "projects/my-project-v2-685248eda405663f/" + hash + ".tar-gz",
+		},
+		{
+			label:       "No extension",
+			prefix:      KeyPrefix("projects", ""),
+			file:        models.FileData{Filename: "README", Content: []byte("hello")},
+			expectedKey: "projects/untitled-e3b0c44298fc1c14/" + hash,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			key := MediaKey(test.prefix, test.file)
+			if key != test.expectedKey {
+				t.Errorf("expected %v, got %v", test.expectedKey, key)
+			}
+		})
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	for _, test := range []struct {
+		label string
+		a     string
+		b     string
+	}{
+		{label: "Symbols removed by sanitizing", a: "C++ Engine", b: "C Engine"},
+		{label: "Case", a: "Personal Website", b: "personal website"},
+		{label: "Non-Latin titles", a: "ウェブサイト", b: "Сайт"},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			prefixA, prefixB := KeyPrefix("projects", test.a), KeyPrefix("projects", test.b)
+			if prefixA == prefixB {
+				t.Errorf("expected different prefixes for %q and %q, got %v", test.a, test.b, prefixA)
+			}
+		})
+	}
+}
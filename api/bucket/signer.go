@@ -0,0 +1,154 @@
+package bucket
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// URLSigner returns a URL the object at key can be fetched from until expires
+type URLSigner interface {
+	SignURL(ctx context.Context, key string, expires time.Time) (string, error)
+	// ObjectKey returns the key of an object from a URL the signer returned
+	ObjectKey(signedURL string) (string, bool)
+}
+
+// S3URLSigner signs URLs with S3 presigned GET requests
+type S3URLSigner struct {
+	Client     *s3.Client
+	BucketName string
+}
+
+func (s *S3URLSigner) SignURL(ctx context.Context, key string, expires time.Time) (string, error) {
+	inputGet := &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	}
+
+	presignClient := s3.NewPresignClient(s.Client)
+	presignedReq, err := presignClient.PresignGetObject(ctx, inputGet, func(opts *s3.PresignOptions) {
+		opts.Expires = time.Until(expires)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return presignedReq.URL, nil
+}
+
+func (s *S3URLSigner) ObjectKey(signedURL string) (string, bool) {
+	ref := models.ParseMediaLink(signedURL)
+	if !ref.IsS3() || ref.Bucket != s.BucketName {
+		return "", false
+	}
+	return ref.Key, true
+}
+
+// CDNURLSigner serves objects from a CDN in front of the bucket, e.g. a
+// CloudFront distribution. When KeyPairID and PrivateKey are set URLs are
+// signed with a CloudFront canned policy, otherwise the distribution must
+// serve the objects publicly.
+type CDNURLSigner struct {
+	BaseURL    string
+	KeyPairID  string
+	PrivateKey *rsa.PrivateKey
+}
+
+// NewCDNURLSigner returns a signer for baseURL. privateKey is the PEM encoded
+// RSA key of the key pair, PKCS #1 or PKCS #8, and may be empty along with
+// keyPairID for unsigned URLs.
+func NewCDNURLSigner(baseURL string, keyPairID string, privateKey []byte) (*CDNURLSigner, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil || (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid CDN base URL: %s", baseURL)
+	}
+	signer := &CDNURLSigner{BaseURL: strings.TrimSuffix(baseURL, "/")}
+
+	if keyPairID == "" && len(privateKey) == 0 {
+		return signer, nil
+	}
+	if keyPairID == "" || len(privateKey) == 0 {
+		return nil, errors.New("CDN key pair ID and private key must be set together")
+	}
+
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("CDN private key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		pkcs8Key, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := pkcs8Key.(*rsa.PrivateKey)
+		if pkcs8Err != nil || !ok {
+			return nil, fmt.Errorf("CDN private key is not an RSA key: %w", err)
+		}
+		key = rsaKey
+	}
+
+	signer.KeyPairID = keyPairID
+	signer.PrivateKey = key
+	return signer, nil
+}
+
+func (c *CDNURLSigner) SignURL(ctx context.Context, key string, expires time.Time) (string, error) {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	resource := c.BaseURL + "/" + strings.Join(segments, "/")
+	if c.PrivateKey == nil {
+		return resource, nil
+	}
+
+	hash := sha1.Sum(cannedPolicy(resource, expires))
+	signature, err := rsa.SignPKCS1v15(nil, c.PrivateKey, crypto.SHA1, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign CDN URL: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("Expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("Signature", cloudFrontBase64(signature))
+	query.Set("Key-Pair-Id", c.KeyPairID)
+	return resource + "?" + query.Encode(), nil
+}
+
+func (c *CDNURLSigner) ObjectKey(signedURL string) (string, bool) {
+	resource, _, _ := strings.Cut(signedURL, "?")
+	escapedKey, ok := strings.CutPrefix(resource, c.BaseURL+"/")
+	if !ok {
+		return "", false
+	}
+	key, err := url.PathUnescape(escapedKey)
+	if err != nil || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// cannedPolicy returns the CloudFront canned policy for resource, which only
+// limits when the URL expires. CloudFront rebuilds the policy from the URL, so
+// it must match this exact format without whitespace.
+func cannedPolicy(resource string, expires time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"Statement":[{"Resource":"%s","Condition":{"DateLessThan":{"AWS:EpochTime":%d}}}]}`, resource, expires.Unix()))
+}
+
+// cloudFrontBase64 encodes b with the URL safe alphabet CloudFront expects,
+// which differs from base64.URLEncoding
+func cloudFrontBase64(b []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(b))
+}
@@ -0,0 +1,120 @@
+package bucket
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCDNURLSigner(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes})
+	expires := time.Unix(1767225600, 0)
+
+	for _, test := range []struct {
+		label       string
+		baseURL     string
+		keyPairID   string
+		privateKey  []byte
+		key         string
+		expectedURL string
+		expectError bool
+	}{
+		{
+			label:       "Unsigned",
+			baseURL:     "https://cdn.example.com/",
+			key:         "projects/personal-website/abc.png",
+			expectedURL: "https://cdn.example.com/projects/personal-website/abc.png",
+		},
+		{
+			label:       "Signed with PKCS #1 key",
+			baseURL:     "https://d111111abcdef8.cloudfront.net",
+			keyPairID:   "K2JCJMDEHXQW5F",
+			privateKey:  pkcs1,
+			key:         "projects/personal-website/abc.png",
+			expectedURL: "https://d111111abcdef8.cloudfront.net/projects/personal-website/abc.png",
+		},
+		{
+			label:       "Signed with PKCS #8 key and escaped key",
+			baseURL:     "https://d111111abcdef8.cloudfront.net",
+			keyPairID:   "K2JCJMDEHXQW5F",
+			privateKey:  pkcs8,
+			key:         "old screenshot.png",
+			expectedURL: "https://d111111abcdef8.cloudfront.net/old%20screenshot.png",
+		},
+		{
+			label:       "Key pair ID without private key",
+			baseURL:     "https://cdn.example.com",
+			keyPairID:   "K2JCJMDEHXQW5F",
+			expectError: true,
+		},
+		{
+			label:       "Invalid base URL",
+			baseURL:     "cdn.example.com",
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			signer, err := NewCDNURLSigner(test.baseURL, test.keyPairID, test.privateKey)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			signedURL, err := signer.SignURL(context.Background(), test.key, expires)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resource, rawQuery, _ := strings.Cut(signedURL, "?")
+			if resource != test.expectedURL {
+				t.Errorf("expected %v, got %v", test.expectedURL, resource)
+			}
+			if test.keyPairID == "" {
+				if rawQuery != "" {
+					t.Errorf("expected an unsigned URL, got %v", signedURL)
+				}
+				return
+			}
+
+			query, err := url.ParseQuery(rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.Get("Expires") != "1767225600" || query.Get("Key-Pair-Id") != test.keyPairID {
+				t.Errorf("unexpected query: %v", rawQuery)
+			}
+			signature := strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(query.Get("Signature"))
+			decoded, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil {
+				t.Fatalf("signature is not CloudFront base64: %v", err)
+			}
+			policy := `{"Statement":[{"Resource":"` + test.expectedURL + `","Condition":{"DateLessThan":{"AWS:EpochTime":1767225600}}}]}`
+			hash := sha1.Sum([]byte(policy))
+			if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA1, hash[:], decoded); err != nil {
+				t.Errorf("signature does not match the canned policy: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Command archive exports every work, skillsTools and project item, and
+// optionally their media, to a zip archive, and imports such an archive into
+// a table and bucket.
+//
+// Usage:
+//
+//	go run ./cmd/archive export -table PersonalWebsiteTable -bucket my-bucket [-media] [-o portfolio.zip]
+//	go run ./cmd/archive import -table PersonalWebsiteTable -bucket my-bucket [-dry-run] portfolio.zip
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thomasmendez/personal-website-backend/api/archive"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+)
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: archive export|import [flags]")
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	tableName := flags.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table name")
+	bucketName := flags.String("bucket", os.Getenv("BUCKET_NAME"), "S3 bucket name")
+	region := flags.String("region", os.Getenv("REGION"), "AWS region")
+	endpoint := flags.String("endpoint", "", "DynamoDB endpoint, e.g. http://localhost:8000")
+	withMedia := flags.Bool("media", false, "include the media items reference in the export")
+	output := flags.String("o", "portfolio.zip", "file the export is written to")
+	dryRun := flags.Bool("dry-run", false, "report the differences between the archive and the table without importing it")
+	flags.Parse(os.Args[2:])
+
+	if *tableName == "" || *bucketName == "" {
+		log.Fatal("error in configuration: -table and -bucket are required")
+	}
+	if command == "import" && flags.NArg() != 1 {
+		log.Fatal("error in configuration: import requires the archive file")
+	}
+
+	ctx := context.Background()
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(*region))
+	if err != nil {
+		log.Fatal("error loading AWS config: ", err)
+	}
+
+	options := func(options *dynamodb.Options) {}
+	if *endpoint != "" {
+		options = func(options *dynamodb.Options) {
+			options.BaseEndpoint = aws.String(*endpoint)
+		}
+	}
+	db := database.NewDatabase(awsConfig, options)
+	b := bucket.NewBucket(awsConfig, *bucketName)
+
+	var report any
+	if command == "export" {
+		report, err = exportArchive(ctx, db, b, *tableName, *output, *withMedia)
+	} else {
+		report, err = importArchive(ctx, db, b, *tableName, flags.Arg(0), *dryRun)
+	}
+	if err != nil {
+		log.Fatalf("error in %sing archive: %v", command, err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("error in writing report: ", err)
+	}
+}
+
+func exportArchive(ctx context.Context, db *database.Database, b *bucket.Bucket, tableName string, output string, withMedia bool) (archive.Manifest, error) {
+	file, err := os.Create(output)
+	if err != nil {
+		return archive.Manifest{}, err
+	}
+	manifest, err := archive.Export(ctx, db, b, tableName, file, withMedia)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return manifest, err
+}
+
+// importArchive imports the archive file input. Media is checked against the
+// content types in ALLOWED_CONTENT_TYPES, as the API checks uploads.
+func importArchive(ctx context.Context, db *database.Database, b *bucket.Bucket, tableName string, input string, dryRun bool) (archive.Report, error) {
+	allowed, err := mediatype.ParseAllowed(os.Getenv("ALLOWED_CONTENT_TYPES"))
+	if err != nil {
+		return archive.Report{}, fmt.Errorf("ALLOWED_CONTENT_TYPES: %w", err)
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return archive.Report{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return archive.Report{}, err
+	}
+	a, err := archive.Read(file, info.Size())
+	if err != nil {
+		return archive.Report{}, err
+	}
+	return archive.Import(ctx, db, b, tableName, a, archive.ImportOptions{
+		DryRun:              dryRun,
+		AllowedContentTypes: allowed,
+	})
+}
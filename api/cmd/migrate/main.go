@@ -0,0 +1,85 @@
+// Command migrate applies the data migrations that have not been applied to
+// the table. With -endpoint, e.g. for DynamoDB local, or -create-table it
+// first creates the table and its global secondary indexes when they do not
+// exist. Deployed tables are left to CloudFormation, which would otherwise
+// report the indexes created here as drift.
+//
+// Usage:
+//
+//	go run ./cmd/migrate -table PersonalWebsiteTable -bucket my-bucket [-dry-run] [-create-table]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/migrations"
+)
+
+func main() {
+	tableName := flag.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table name")
+	bucketName := flag.String("bucket", os.Getenv("BUCKET_NAME"), "S3 bucket name")
+	region := flag.String("region", os.Getenv("REGION"), "AWS region")
+	endpoint := flag.String("endpoint", "", "DynamoDB endpoint, e.g. http://localhost:8000")
+	dryRun := flag.Bool("dry-run", false, "report what would be created and migrated without writing to the table")
+	createTable := flag.Bool("create-table", false, "create the table or its indexes when they do not exist, the default with -endpoint")
+	flag.Parse()
+
+	if *tableName == "" || *bucketName == "" {
+		log.Fatal("error in configuration: -table and -bucket are required")
+	}
+	// tables of an endpoint are local, deployed tables are managed by CloudFormation
+	if *endpoint != "" && !flagSet("create-table") {
+		*createTable = true
+	}
+
+	ctx := context.Background()
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(*region))
+	if err != nil {
+		log.Fatal("error loading AWS config: ", err)
+	}
+
+	options := func(options *dynamodb.Options) {}
+	if *endpoint != "" {
+		options = func(options *dynamodb.Options) {
+			options.BaseEndpoint = aws.String(*endpoint)
+		}
+	}
+
+	media := bucket.NewBucket(awsConfig, *bucketName)
+	report, err := migrations.Run(ctx, database.NewDatabase(awsConfig, options), *tableName, migrations.All(media), migrations.Options{
+		DryRun:    *dryRun,
+		SkipTable: !*createTable,
+	})
+	if err != nil {
+		log.Print("error in migrating table: ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("error in writing report: ", err)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// flagSet reports whether the flag name was set on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
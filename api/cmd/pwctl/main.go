@@ -0,0 +1,100 @@
+// Command pwctl lists, gets, creates, updates and deletes work, projects and
+// skillsTools, and uploads their media, with the database and bucket packages
+// the API uses.
+//
+// Usage:
+//
+//	go run ./cmd/pwctl [-profile name] [-o table|json|yaml] <resource> <command> [flags] [args]
+//
+// Resources are work, projects and skillsTools, and commands are:
+//
+//	list                                  list every item
+//	get <sortValue>                       print an item
+//	create -f item.yaml                   create an item from a JSON or YAML file, - for stdin
+//	update -f item.yaml                   replace an item from a JSON or YAML file, keeping its media
+//	delete <sortValue>                    delete an item and its media
+//	upload [-caption c] [-alt a] <sortValue> <file>
+//	                                      set the company logo of work or add to the media of a project
+//
+// The table and bucket come from a profile of the config file, see Profile,
+// and the -table, -bucket, -region and -endpoint flags override it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+)
+
+func main() {
+	profileName := flag.String("profile", os.Getenv("PWCTL_PROFILE"), "profile of the config file, defaults to its default profile")
+	tableName := flag.String("table", "", "DynamoDB table name")
+	bucketName := flag.String("bucket", "", "S3 bucket name")
+	region := flag.String("region", "", "AWS region")
+	endpoint := flag.String("endpoint", "", "DynamoDB endpoint, e.g. http://localhost:8000")
+	format := flag.String("o", "", "output format, table, json or yaml, defaults to table for list and yaml otherwise")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: pwctl [flags] work|projects|skillsTools list|get|create|update|delete|upload [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	r, ok := resources[flag.Arg(0)]
+	if !ok {
+		log.Fatalf("error in command: unknown resource %q, expected work, projects or skillsTools", flag.Arg(0))
+	}
+	name := flag.Arg(1)
+	if *format == "" {
+		*format = formatYAML
+		if name == "list" {
+			*format = formatTable
+		}
+	}
+
+	profile, err := loadProfile(configPath(), *profileName)
+	if err != nil {
+		log.Fatal("error in configuration: ", err)
+	}
+	profile = profile.merge(Profile{Table: *tableName, Bucket: *bucketName, Region: *region, Endpoint: *endpoint})
+	if profile.Table == "" || profile.Bucket == "" {
+		log.Fatal("error in configuration: a table and bucket are required, set them in a profile or with -table and -bucket")
+	}
+
+	ctx := context.Background()
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(profile.Region))
+	if err != nil {
+		log.Fatal("error loading AWS config: ", err)
+	}
+
+	options := func(options *dynamodb.Options) {}
+	if profile.Endpoint != "" {
+		options = func(options *dynamodb.Options) {
+			options.BaseEndpoint = aws.String(profile.Endpoint)
+		}
+	}
+	c := &client{
+		db:        database.NewDatabase(awsConfig, options),
+		bucket:    bucket.NewBucket(awsConfig, profile.Bucket),
+		tableName: profile.Table,
+	}
+
+	out, err := r.run(ctx, c, name, flag.Args()[2:])
+	if err != nil {
+		log.Fatalf("error in %s %s: %v", flag.Arg(0), name, err)
+	}
+	if err := writeOutput(os.Stdout, *format, out); err != nil {
+		log.Fatal("error in writing output: ", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// readMediaFile reads the file at path and identifies it by its content,
+// which must be one of allowed, like files uploaded to the API. SVGs are
+// sanitized.
+func readMediaFile(path string, allowed []string) (models.FileData, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return models.FileData{}, err
+	}
+
+	contentType, content, err := mediatype.Prepare(content, allowed)
+	if err != nil {
+		return models.FileData{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return models.FileData{
+		Filename:    filepath.Base(path),
+		Content:     content,
+		ContentType: contentType,
+	}, nil
+}
+
+// uploadCompanyLogo uploads file and sets it as the company logo of work, in
+// the same way the API uploads logos
+func uploadCompanyLogo(ctx context.Context, c *client, work *models.Work, file models.FileData, opts uploadOptions) error {
+	ref, err := c.bucket.UploadCompanyLogo(ctx, work.SortValue, file)
+	if err != nil {
+		return err
+	}
+	work.CompanyLogo = nil
+	work.CompanyLogoRef = ref
+	return nil
+}
+
+// uploadProjectMedia uploads file and its resized variants and appends it to
+// the media gallery of project, in the same place the API uploads media
+func uploadProjectMedia(ctx context.Context, c *client, project *models.Project, file models.FileData, opts uploadOptions) error {
+	key := bucket.MediaKey(bucket.KeyPrefix(bucket.ProjectMediaPrefix, project.SortValue), file)
+	id := bucket.MediaItemID(key)
+	if project.MediaIndex(id) != -1 {
+		return fmt.Errorf("%s is already in the media gallery of project %s as %s", file.Filename, project.SortValue, id)
+	}
+
+	ref, variants, err := c.bucket.UploadMedia(ctx, key, file)
+	if err != nil {
+		return err
+	}
+	item := models.MediaItem{
+		ID:          id,
+		Ref:         ref,
+		ContentType: file.ContentType,
+		Variants:    variants,
+	}
+	if opts.caption != "" {
+		item.Caption = &opts.caption
+	}
+	if opts.altText != "" {
+		item.AltText = &opts.altText
+	}
+	project.Media = append(project.Media, item)
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// output formats of the -o flag
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// output is the result of a command, printed as a table of rows or as the
+// JSON or YAML of value
+type output struct {
+	value   any
+	columns []string
+	rows    [][]string
+}
+
+// writeOutput prints out to w in format
+func writeOutput(w io.Writer, format string, out output) error {
+	switch format {
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, len(out.columns))
+		for i, column := range out.columns {
+			header[i] = strings.ToUpper(column)
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range out.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out.value)
+	case formatYAML:
+		jsonBytes, err := json.Marshal(out.value)
+		if err != nil {
+			return err
+		}
+		// JSON is YAML, so decoding it into a node keeps the order of the fields
+		var node yaml.Node
+		if err := yaml.Unmarshal(jsonBytes, &node); err != nil {
+			return err
+		}
+		clearYAMLStyle(&node)
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unknown output format %q, expected table, json or yaml", format)
+}
+
+// clearYAMLStyle resets the flow and quoted styles of JSON so nodes are
+// encoded in block style
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// readItem decodes the JSON or YAML file at path, or stdin for -, into an
+// item. Fields are matched by their json names, and unknown fields are an
+// error so typos are not silently dropped.
+func readItem[T any](path string) (T, error) {
+	var item T
+	var data []byte
+	var err error
+	switch path {
+	case "":
+		return item, fmt.Errorf("-f is required")
+	case "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return item, err
+	}
+
+	// YAML is a superset of JSON, so both are decoded as YAML
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return item, fmt.Errorf("invalid item %s: %w", path, err)
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return item, fmt.Errorf("invalid item %s: %w", path, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&item); err != nil {
+		return item, fmt.Errorf("invalid item %s: %w", path, err)
+	}
+	return item, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestReadItem(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	expected := models.SkillsTools{
+		SortValue:  "Software Engineering",
+		Categories: []models.Category{{Category: "Languages", List: []string{"Go", "TypeScript"}}},
+	}
+
+	for _, test := range []struct {
+		label       string
+		path        string
+		expectError bool
+	}{
+		{
+			label: "JSON",
+			path:  write("item.json", `{"sortValue": "Software Engineering", "categories": [{"category": "Languages", "list": ["Go", "TypeScript"]}]}`),
+		},
+		{
+			label: "YAML",
+			path: write("item.yaml", `sortValue: Software Engineering
+categories:
+  - category: Languages
+    list: [Go, TypeScript]
+`),
+		},
+		{
+			label:       "Unknown field",
+			path:        write("typo.yaml", "sortValue: Software Engineering\ncategory: Languages\n"),
+			expectError: true,
+		},
+		{
+			label:       "No file",
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			item, err := readItem[models.SkillsTools](test.path)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(item, expected) {
+				t.Errorf("expected %+v, got %+v", expected, item)
+			}
+		})
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	out := output{
+		value:   map[string]any{"sortValue": "2020-01-01", "jobTitle": "Software Engineer"},
+		columns: []string{"sortValue", "jobTitle"},
+		rows:    [][]string{{"2020-01-01", "Software Engineer"}},
+	}
+
+	for _, test := range []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   formatTable,
+			expected: "SORTVALUE   JOBTITLE\n2020-01-01  Software Engineer\n",
+		},
+		{
+			format:   formatJSON,
+			expected: "{\n  \"jobTitle\": \"Software Engineer\",\n  \"sortValue\": \"2020-01-01\"\n}\n",
+		},
+		{
+			format:   formatYAML,
+			expected: "jobTitle: Software Engineer\nsortValue: \"2020-01-01\"\n",
+		},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOutput(&buf, test.format, out); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("expected\n%s\ngot\n%s", test.expected, buf.String())
+			}
+		})
+	}
+
+	if err := writeOutput(&bytes.Buffer{}, "xml", out); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestReadMediaFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for _, test := range []struct {
+		label               string
+		path                string
+		allowed             []string
+		expectedContentType string
+		expectedContent     string
+	}{
+		{
+			label:               "Content type of the content",
+			path:                write("logo.PNG", "\x89PNG\r\n\x1a\n"),
+			allowed:             mediatype.Detectable,
+			expectedContentType: "image/png",
+			expectedContent:     "\x89PNG\r\n\x1a\n",
+		},
+		{
+			label:               "Extension is ignored",
+			path:                write("document.png", "%PDF-1.4"),
+			allowed:             mediatype.Detectable,
+			expectedContentType: "application/pdf",
+			expectedContent:     "%PDF-1.4",
+		},
+		{
+			label:               "SVG is sanitized",
+			path:                write("diagram.svg", `<svg><script>alert(1)</script></svg>`),
+			allowed:             mediatype.Detectable,
+			expectedContentType: "image/svg+xml",
+			expectedContent:     "<svg></svg>",
+		},
+		{
+			label:   "Not detectable",
+			path:    write("page.html", "<html></html>"),
+			allowed: mediatype.Detectable,
+		},
+		{
+			label:   "Not allowed",
+			path:    write("document.pdf", "%PDF-1.4"),
+			allowed: []string{"image/png"},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			file, err := readMediaFile(test.path, test.allowed)
+			if test.expectedContentType == "" {
+				if !errors.Is(err, mediatype.ErrUnsupported) {
+					t.Errorf("expected an unsupported media type error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.ContentType != test.expectedContentType || string(file.Content) != test.expectedContent || file.Filename != filepath.Base(test.path) {
+				t.Errorf("expected %s %q, got %s %s %q", test.expectedContentType, test.expectedContent, file.Filename, file.ContentType, file.Content)
+			}
+		})
+	}
+}
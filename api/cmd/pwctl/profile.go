@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is the table and bucket of an environment. Profiles are read from
+// the config file, e.g.
+//
+//	default: local
+//	profiles:
+//	  local:
+//	    table: PersonalWebsiteTable
+//	    bucket: personal-website-local
+//	    region: us-east-2
+//	    endpoint: http://localhost:8000
+//	  prd:
+//	    table: PersonalWebsiteTable
+//	    bucket: personal-website-prd
+//	    region: us-east-2
+type Profile struct {
+	Table  string `yaml:"table"`
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	// Endpoint is the DynamoDB endpoint, e.g. of DynamoDB local
+	Endpoint string `yaml:"endpoint"`
+}
+
+type configFile struct {
+	// Default is the profile used when none is selected
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// configPath returns the path of the config file, $PWCTL_CONFIG or
+// pwctl/config.yaml in the user config directory
+func configPath() string {
+	if path := os.Getenv("PWCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pwctl", "config.yaml")
+}
+
+// loadProfile returns the profile name of the config file at path, or its
+// default profile when name is empty, on top of the TABLE_NAME, BUCKET_NAME
+// and REGION env. Without a config file only a named profile is an error.
+func loadProfile(path string, name string) (Profile, error) {
+	profile := Profile{
+		Table:  os.Getenv("TABLE_NAME"),
+		Bucket: os.Getenv("BUCKET_NAME"),
+		Region: os.Getenv("REGION"),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && name == "" {
+		return profile, nil
+	}
+	if err != nil {
+		return profile, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return profile, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = cfg.Default
+	}
+	if name == "" {
+		return profile, nil
+	}
+	selected, ok := cfg.Profiles[name]
+	if !ok {
+		return profile, fmt.Errorf("unknown profile %q in %s", name, path)
+	}
+	return profile.merge(selected), nil
+}
+
+// merge returns p with the fields that are set in other
+func (p Profile) merge(other Profile) Profile {
+	if other.Table != "" {
+		p.Table = other.Table
+	}
+	if other.Bucket != "" {
+		p.Bucket = other.Bucket
+	}
+	if other.Region != "" {
+		p.Region = other.Region
+	}
+	if other.Endpoint != "" {
+		p.Endpoint = other.Endpoint
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	t.Setenv("TABLE_NAME", "EnvTable")
+	t.Setenv("BUCKET_NAME", "env-bucket")
+	t.Setenv("REGION", "us-east-2")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(path, []byte(`default: local
+profiles:
+  local:
+    table: PersonalWebsiteTable
+    endpoint: http://localhost:8000
+  prd:
+    bucket: prd-bucket
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("profiles:\n  local:\n    tabel: Typo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		label           string
+		path            string
+		name            string
+		expectedProfile Profile
+		expectError     bool
+	}{
+		{
+			label:           "No config file",
+			path:            filepath.Join(dir, "missing.yaml"),
+			expectedProfile: Profile{Table: "EnvTable", Bucket: "env-bucket", Region: "us-east-2"},
+		},
+		{
+			label:           "Default profile",
+			path:            path,
+			expectedProfile: Profile{Table: "PersonalWebsiteTable", Bucket: "env-bucket", Region: "us-east-2", Endpoint: "http://localhost:8000"},
+		},
+		{
+			label:           "Named profile",
+			path:            path,
+			name:            "prd",
+			expectedProfile: Profile{Table: "EnvTable", Bucket: "prd-bucket", Region: "us-east-2"},
+		},
+		{
+			label:       "Unknown profile",
+			path:        path,
+			name:        "stg",
+			expectError: true,
+		},
+		{
+			label:       "Named profile without a config file",
+			path:        filepath.Join(dir, "missing.yaml"),
+			name:        "local",
+			expectError: true,
+		},
+		{
+			label:       "Unknown field",
+			path:        invalidPath,
+			name:        "local",
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			profile, err := loadProfile(test.path, test.name)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", profile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if profile != test.expectedProfile {
+				t.Errorf("expected %+v, got %+v", test.expectedProfile, profile)
+			}
+		})
+	}
+}
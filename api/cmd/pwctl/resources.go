@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+var errNotFound = errors.New("not found")
+
+// client is the table and bucket commands read and write
+type client struct {
+	db        *database.Database
+	bucket    *bucket.Bucket
+	tableName string
+}
+
+type command interface {
+	run(ctx context.Context, c *client, name string, args []string) (output, error)
+}
+
+// uploadOptions are the flags of the upload command
+type uploadOptions struct {
+	caption string
+	altText string
+}
+
+// resource are the commands of the items of a partition
+type resource[T any] struct {
+	partitionKey string
+	columns      []string
+	row          func(T) []string
+	// key returns the personalWebsiteType and sortValue of item
+	key    func(item *T) (*string, *string)
+	list   func(ctx context.Context, svc dynamodb.QueryAPIClient, tableName string) ([]T, error)
+	create func(ctx context.Context, svc *dynamodb.Client, tableName string, item T) (T, error)
+	update func(ctx context.Context, svc *dynamodb.Client, tableName string, item T) (T, error)
+	// mediaKeys returns the S3 keys of the media of item
+	mediaKeys func(item T) []string
+	// setMediaRefs replaces the media links of a new item with references
+	setMediaRefs func(c *client, item *T)
+	// keepMedia sets the media of an updated item to the media of existing,
+	// media is only changed with upload
+	keepMedia func(item *T, existing T)
+	// resolveLinks sets the media links of item to the URLs of its references
+	resolveLinks func(c *client, item *T)
+	// upload adds file to the media of item, nil for items without media
+	upload func(ctx context.Context, c *client, item *T, file models.FileData, opts uploadOptions) error
+}
+
+// resources are the commands of each resource by the name of its endpoint
+var resources = map[string]command{
+	"work": resource[models.Work]{
+		partitionKey: "Work",
+		columns:      []string{"sortValue", "jobTitle", "company", "startDate", "endDate", "companyLogo"},
+		row: func(w models.Work) []string {
+			return []string{w.SortValue, w.JobTitle, w.Company, w.StartDate, w.EndDate, refKey(w.GetCompanyLogoRef())}
+		},
+		key:    func(w *models.Work) (*string, *string) { return &w.PersonalWebsiteType, &w.SortValue },
+		list:   database.GetWork,
+		create: database.PostWork,
+		update: database.UpdateWork,
+		mediaKeys: func(w models.Work) []string {
+			if ref := w.GetCompanyLogoRef(); ref.IsS3() {
+				return []string{ref.Key}
+			}
+			return nil
+		},
+		setMediaRefs: func(c *client, w *models.Work) {
+			if w.CompanyLogo != nil {
+				w.CompanyLogoRef = c.bucket.ParseMediaLink(*w.CompanyLogo)
+				w.CompanyLogo = nil
+			}
+		},
+		keepMedia: func(w *models.Work, existing models.Work) {
+			w.CompanyLogo, w.CompanyLogoRef = existing.CompanyLogo, existing.CompanyLogoRef
+		},
+		resolveLinks: func(c *client, w *models.Work) {
+			if link := mediaLink(c, w.GetCompanyLogoRef()); link != "" {
+				w.CompanyLogo = &link
+			}
+		},
+		upload: uploadCompanyLogo,
+	},
+	"projects": resource[models.Project]{
+		partitionKey: "Projects",
+		columns:      []string{"sortValue", "name", "category", "startDate", "endDate", "media"},
+		row: func(p models.Project) []string {
+			return []string{p.SortValue, p.Name, p.Category, p.StartDate, p.EndDate, strconv.Itoa(len(p.Media))}
+		},
+		key:       func(p *models.Project) (*string, *string) { return &p.PersonalWebsiteType, &p.SortValue },
+		list:      database.GetProjects,
+		create:    database.PostProject,
+		update:    database.UpdateProject,
+		mediaKeys: func(p models.Project) []string { return p.MediaFileNames() },
+		setMediaRefs: func(c *client, p *models.Project) {
+			p.SetMediaRefs(c.bucket.ParseMediaLink)
+		},
+		keepMedia: func(p *models.Project, existing models.Project) {
+			p.MediaLink, p.MediaRef = existing.MediaLink, existing.MediaRef
+			p.MediaVariants, p.MediaPoster, p.MediaPosterTime = existing.MediaVariants, existing.MediaPoster, existing.MediaPosterTime
+			p.Media = existing.Media
+		},
+		resolveLinks: func(c *client, p *models.Project) {
+			if link := mediaLink(c, p.GetMediaRef()); link != "" {
+				p.MediaLink = &link
+			}
+			for i := range p.Media {
+				p.Media[i].MediaLink = mediaLink(c, p.Media[i].GetRef())
+			}
+		},
+		upload: uploadProjectMedia,
+	},
+	"skillsTools": resource[models.SkillsTools]{
+		partitionKey: "SkillsTools",
+		columns:      []string{"sortValue", "categories"},
+		row: func(s models.SkillsTools) []string {
+			categories := make([]string, len(s.Categories))
+			for i, category := range s.Categories {
+				categories[i] = category.Category
+			}
+			return []string{s.SortValue, strings.Join(categories, ", ")}
+		},
+		key:          func(s *models.SkillsTools) (*string, *string) { return &s.PersonalWebsiteType, &s.SortValue },
+		list:         database.GetSkillsTools,
+		create:       database.PostSkillsTools,
+		update:       database.UpdateSkillsTools,
+		mediaKeys:    func(models.SkillsTools) []string { return nil },
+		setMediaRefs: func(*client, *models.SkillsTools) {},
+		keepMedia:    func(*models.SkillsTools, models.SkillsTools) {},
+		resolveLinks: func(*client, *models.SkillsTools) {},
+	},
+}
+
+func (r resource[T]) run(ctx context.Context, c *client, name string, args []string) (output, error) {
+	switch name {
+	case "list":
+		items, err := r.list(ctx, c.db.Client, c.tableName)
+		if err != nil {
+			return output{}, err
+		}
+		for i := range items {
+			r.resolveLinks(c, &items[i])
+		}
+		return r.output(items, items...), nil
+
+	case "get":
+		if len(args) != 1 {
+			return output{}, fmt.Errorf("usage: get <sortValue>")
+		}
+		item, err := r.get(ctx, c, args[0])
+		if err != nil {
+			return output{}, err
+		}
+		r.resolveLinks(c, &item)
+		return r.output(item, item), nil
+
+	case "create", "update":
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		path := flags.String("f", "", "JSON or YAML file of the item, - for stdin")
+		if err := flags.Parse(args); err != nil {
+			return output{}, err
+		}
+		item, err := readItem[T](*path)
+		if err != nil {
+			return output{}, err
+		}
+		partitionKey, sortValue := r.key(&item)
+		if *partitionKey == "" {
+			*partitionKey = r.partitionKey
+		}
+		if *partitionKey != r.partitionKey {
+			return output{}, fmt.Errorf("personalWebsiteType must be %s, got %s", r.partitionKey, *partitionKey)
+		}
+		if *sortValue == "" {
+			return output{}, fmt.Errorf("sortValue cannot be empty")
+		}
+
+		existing, err := r.get(ctx, c, *sortValue)
+		if err != nil && !errors.Is(err, errNotFound) {
+			return output{}, err
+		}
+		if name == "create" {
+			if err == nil {
+				return output{}, fmt.Errorf("%s %s already exists", r.partitionKey, *sortValue)
+			}
+			r.setMediaRefs(c, &item)
+			item, err = r.create(ctx, c.db.Client, c.tableName, item)
+		} else {
+			if err != nil {
+				return output{}, err
+			}
+			r.keepMedia(&item, existing)
+			item, err = r.update(ctx, c.db.Client, c.tableName, item)
+		}
+		if err != nil {
+			return output{}, err
+		}
+		r.resolveLinks(c, &item)
+		return r.output(item, item), nil
+
+	case "delete":
+		if len(args) != 1 {
+			return output{}, fmt.Errorf("usage: delete <sortValue>")
+		}
+		existing, err := r.get(ctx, c, args[0])
+		if err != nil {
+			return output{}, err
+		}
+		if err := database.DeleteItem(ctx, c.db.Client, c.tableName, r.partitionKey, args[0]); err != nil {
+			return output{}, err
+		}
+		// media is only deleted once the item no longer references it
+		deleteMedia(ctx, c, r.mediaKeys(existing))
+		return r.output(existing, existing), nil
+
+	case "upload":
+		if r.upload == nil {
+			return output{}, fmt.Errorf("%s have no media", r.partitionKey)
+		}
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		var opts uploadOptions
+		flags.StringVar(&opts.caption, "caption", "", "caption of project media")
+		flags.StringVar(&opts.altText, "alt", "", "alt text of project media")
+		if err := flags.Parse(args); err != nil {
+			return output{}, err
+		}
+		if flags.NArg() != 2 {
+			return output{}, fmt.Errorf("usage: upload [-caption text] [-alt text] <sortValue> <file>")
+		}
+		allowed, err := mediatype.ParseAllowed(os.Getenv("ALLOWED_CONTENT_TYPES"))
+		if err != nil {
+			return output{}, fmt.Errorf("ALLOWED_CONTENT_TYPES: %w", err)
+		}
+		file, err := readMediaFile(flags.Arg(1), allowed)
+		if err != nil {
+			return output{}, err
+		}
+		existing, err := r.get(ctx, c, flags.Arg(0))
+		if err != nil {
+			return output{}, err
+		}
+
+		item := existing
+		if err := r.upload(ctx, c, &item, file, opts); err != nil {
+			return output{}, err
+		}
+		item, err = r.update(ctx, c.db.Client, c.tableName, item)
+		if err != nil {
+			return output{}, err
+		}
+		var replaced []string
+		for _, key := range r.mediaKeys(existing) {
+			if !slices.Contains(r.mediaKeys(item), key) {
+				replaced = append(replaced, key)
+			}
+		}
+		deleteMedia(ctx, c, replaced)
+		r.resolveLinks(c, &item)
+		return r.output(item, item), nil
+	}
+	return output{}, fmt.Errorf("unknown command %q, expected list, get, create, update, delete or upload", name)
+}
+
+// get returns the item sortValue, or errNotFound
+func (r resource[T]) get(ctx context.Context, c *client, sortValue string) (T, error) {
+	var item T
+	if err := database.GetItem(ctx, c.db.Client, c.tableName, r.partitionKey, sortValue, &item); err != nil {
+		return item, err
+	}
+	if _, key := r.key(&item); *key == "" {
+		return item, fmt.Errorf("%s %s %w", r.partitionKey, sortValue, errNotFound)
+	}
+	return item, nil
+}
+
+func (r resource[T]) output(value any, items ...T) output {
+	out := output{value: value, columns: r.columns}
+	for _, item := range items {
+		out.rows = append(out.rows, r.row(item))
+	}
+	return out
+}
+
+// mediaLink returns the unsigned URL of an object in the bucket or the
+// external link of ref, which create and update read back as the same ref
+func mediaLink(c *client, ref *models.MediaRef) string {
+	if ref == nil {
+		return ""
+	}
+	if ref.IsS3() {
+		return c.bucket.ObjectURL(ref.Key)
+	}
+	return ref.URL
+}
+
+func refKey(ref *models.MediaRef) string {
+	if ref == nil {
+		return ""
+	}
+	if ref.IsS3() {
+		return ref.Key
+	}
+	return ref.URL
+}
+
+// deleteMedia deletes the objects at keys. Errors are logged since the item
+// is already saved, and leftover objects are found by cmd/reconcile.
+func deleteMedia(ctx context.Context, c *client, keys []string) {
+	for _, key := range keys {
+		if err := c.bucket.DeleteFileFromS3(ctx, key); err != nil {
+			log.Printf("error in deleting media %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Command reconcile compares the media bucket with the projects table and
+// reports orphaned objects and projects whose media is missing.
+//
+// Usage:
+//
+//	go run ./cmd/reconcile -table PersonalWebsiteTable -bucket my-bucket [-delete]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/reconcile"
+)
+
+func main() {
+	tableName := flag.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table name")
+	bucketName := flag.String("bucket", os.Getenv("BUCKET_NAME"), "S3 bucket name")
+	region := flag.String("region", os.Getenv("REGION"), "AWS region")
+	endpoint := flag.String("endpoint", "", "DynamoDB endpoint, e.g. http://localhost:8000")
+	deleteOrphans := flag.Bool("delete", false, "delete orphaned objects instead of only reporting them")
+	minAge := flag.Duration("min-age", reconcile.DefaultMinAge, "minimum age of an object before it is considered orphaned")
+	flag.Parse()
+
+	if *tableName == "" || *bucketName == "" {
+		log.Fatal("error in configuration: -table and -bucket are required")
+	}
+
+	ctx := context.Background()
+	awsConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(*region))
+	if err != nil {
+		log.Fatal("error loading AWS config: ", err)
+	}
+
+	options := func(options *dynamodb.Options) {}
+	if *endpoint != "" {
+		options = func(options *dynamodb.Options) {
+			options.BaseEndpoint = aws.String(*endpoint)
+		}
+	}
+
+	report, err := reconcile.Run(ctx, database.NewDatabase(awsConfig, options), bucket.NewBucket(awsConfig, *bucketName), *tableName, reconcile.Options{
+		Delete: *deleteOrphans,
+		MinAge: *minAge,
+	})
+	if err != nil {
+		log.Fatal("error in reconciling media: ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("error in writing report: ", err)
+	}
+}
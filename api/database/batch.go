@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// the most requests DynamoDB accepts in one BatchWriteItem
+	batchWriteLimit = 25
+	// how many times a batch is sent before its unprocessed items are an error
+	maxBatchWriteAttempts = 8
+)
+
+// PartitionKeys are the personalWebsiteType of every kind of item in the table
+var PartitionKeys = []string{partitionKeyWork, partitionKeySkillsTools, partitionKeyProjects}
+
+// batchWriteBackoff is how long to wait before sending the unprocessed items
+// of a batch again, doubling from 50ms up to 5s
+var batchWriteBackoff = func(attempt int) time.Duration {
+	return min(50*time.Millisecond<<(attempt-1), 5*time.Second)
+}
+
+// BatchWriteItemAPI is the part of the DynamoDB client BatchPutItems uses
+type BatchWriteItemAPI interface {
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// QueryItems returns every item of partitionKey, following the pages of the
+// query, without unmarshalling them into a model
+func QueryItems(ctx context.Context, svc dynamodb.QueryAPIClient, tableName string, partitionKey string) ([]map[string]types.AttributeValue, error) {
+	items := make([]map[string]types.AttributeValue, 0)
+	paginator := dynamodb.NewQueryPaginator(svc, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("personalWebsiteType = :partitionKey"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partitionKey": &types.AttributeValueMemberS{Value: partitionKey},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("error in DynamoDB Query func: %v", err)
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// BatchPutItems puts items in batches of 25 with BatchWriteItem. Items
+// DynamoDB leaves unprocessed, e.g. when throttled, are sent again with
+// exponential backoff until they are written or the attempts run out.
+func BatchPutItems(ctx context.Context, svc BatchWriteItemAPI, tableName string, items []map[string]types.AttributeValue) error {
+	for start := 0; start < len(items); start += batchWriteLimit {
+		batch := items[start:min(start+batchWriteLimit, len(items))]
+		requests := make([]types.WriteRequest, len(batch))
+		for i, item := range batch {
+			requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+		}
+
+		pending := map[string][]types.WriteRequest{tableName: requests}
+		for attempt := 1; len(pending[tableName]) > 0; attempt++ {
+			if attempt > maxBatchWriteAttempts {
+				return fmt.Errorf("error in DynamoDB BatchWriteItem: %d items unprocessed after %d attempts", len(pending[tableName]), maxBatchWriteAttempts)
+			}
+			if attempt > 1 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(batchWriteBackoff(attempt - 1)):
+				}
+			}
+
+			output, err := svc.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return fmt.Errorf("error in DynamoDB BatchWriteItem: %w", err)
+			}
+			pending = output.UnprocessedItems
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeBatchWriter leaves the last unprocessed items of each call unprocessed
+// until it has been called failures times
+type fakeBatchWriter struct {
+	unprocessed int
+	failures    int
+	calls       int
+	written     map[string]bool
+}
+
+func (f *fakeBatchWriter) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	f.calls++
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for table, requests := range params.RequestItems {
+		if len(requests) > batchWriteLimit {
+			return nil, &types.ResourceNotFoundException{}
+		}
+		processed := requests
+		if f.calls <= f.failures {
+			processed = requests[:max(len(requests)-f.unprocessed, 0)]
+			output.UnprocessedItems[table] = requests[len(processed):]
+		}
+		for _, request := range processed {
+			f.written[request.PutRequest.Item["sortValue"].(*types.AttributeValueMemberS).Value] = true
+		}
+	}
+	return output, nil
+}
+
+func TestBatchPutItems(t *testing.T) {
+	backoff := batchWriteBackoff
+	batchWriteBackoff = func(int) time.Duration { return 0 }
+	defer func() { batchWriteBackoff = backoff }()
+
+	items := make([]map[string]types.AttributeValue, 30)
+	for i := range items {
+		items[i] = map[string]types.AttributeValue{
+			"personalWebsiteType": &types.AttributeValueMemberS{Value: partitionKeyProjects},
+			"sortValue":           &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}
+	}
+
+	for _, test := range []struct {
+		label         string
+		failures      int
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			label:         "Batches of 25",
+			expectedCalls: 2,
+		},
+		{
+			label:         "Retries unprocessed items",
+			failures:      3,
+			expectedCalls: 5,
+		},
+		{
+			label:       "Gives up after the last attempt",
+			failures:    maxBatchWriteAttempts,
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			writer := &fakeBatchWriter{unprocessed: 5, failures: test.failures, written: make(map[string]bool)}
+			err := BatchPutItems(context.Background(), writer, "table", items)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(writer.written) != len(items) {
+				t.Errorf("expected %d items written, got %d", len(items), len(writer.written))
+			}
+			if writer.calls != test.expectedCalls {
+				t.Errorf("expected %d calls, got %d", test.expectedCalls, writer.calls)
+			}
+		})
+	}
+}
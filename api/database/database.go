@@ -137,3 +137,16 @@ func DeleteItem(ctx context.Context, svc *dynamodb.Client, tableName string, per
 	}
 	return nil
 }
+
+// GetTableStatus describes the table and returns its status, e.g. ACTIVE.
+// It is used to check that the table exists and is reachable.
+func GetTableStatus(ctx context.Context, svc *dynamodb.Client, tableName string) (string, error) {
+	output, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		log.Printf("error in DynamoDB DescribeTable func: %v", err)
+		return "", err
+	}
+	return string(output.Table.TableStatus), nil
+}
@@ -13,7 +13,7 @@ import (
 
 const partitionKeyProjects = "Projects"
 
-func GetProjects(ctx context.Context, svc *dynamodb.Client, tableName string) (projects []models.Project, err error) {
+func GetProjects(ctx context.Context, svc dynamodb.QueryAPIClient, tableName string) (projects []models.Project, err error) {
 	projects = make([]models.Project, 0)
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(tableName),
@@ -13,7 +13,7 @@ import (
 
 const partitionKeySkillsTools = "SkillsTools"
 
-func GetSkillsTools(ctx context.Context, svc *dynamodb.Client, tableName string) (skillsTools []models.SkillsTools, err error) {
+func GetSkillsTools(ctx context.Context, svc dynamodb.QueryAPIClient, tableName string) (skillsTools []models.SkillsTools, err error) {
 	skillsTools = make([]models.SkillsTools, 0)
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(tableName),
@@ -13,7 +13,7 @@ import (
 
 const partitionKeyWork = "Work"
 
-func GetWork(ctx context.Context, svc *dynamodb.Client, tableName string) (work []models.Work, err error) {
+func GetWork(ctx context.Context, svc dynamodb.QueryAPIClient, tableName string) (work []models.Work, err error) {
 	work = make([]models.Work, 0)
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(tableName),
@@ -1,10 +1,14 @@
 require (
+	github.com/HugoSmits86/nativewebp v0.9.3
 	github.com/aws/aws-lambda-go v1.49.0
 	github.com/aws/aws-sdk-go-v2 v1.39.1
 	github.com/aws/aws-sdk-go-v2/config v1.31.10
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.20.12
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.50.4
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.88.2
+	github.com/go-pdf/fpdf v0.9.0
+	golang.org/x/image v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -32,4 +36,4 @@ replace gopkg.in/yaml.v2 => gopkg.in/yaml.v2 v2.2.8
 
 module github.com/thomasmendez/personal-website-backend/api
 
-go 1.22
+go 1.22.2
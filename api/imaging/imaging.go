@@ -0,0 +1,162 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// images larger than this only have their metadata stripped. Decoding takes 4
+// bytes per pixel and orienting a JPEG copies the image, so 16 megapixels use
+// about 128MB of the function's 512MB.
+const maxPixels = 16_000_000
+
+const jpegQuality = 82
+
+// Sizes of the generated variants. Variants are never wider than the original.
+var variantWidths = []struct {
+	Name  string
+	Width int
+}{
+	{Name: "thumb", Width: 320},
+	{Name: "w640", Width: 640},
+	{Name: "w1280", Width: 1280},
+	{Name: "w1920", Width: 1920},
+}
+
+// Variant is a resized copy of an uploaded image
+type Variant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Content     []byte
+}
+
+// IsProcessable reports whether variants can be generated for contentType
+func IsProcessable(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// Process decodes the image, strips its metadata and generates the resized
+// variants. It returns the content to store as the original and the variants,
+// each encoded in the original format (GIFs as PNG) and as WebP.
+func Process(content []byte, contentType string) ([]byte, []Variant, error) {
+	if !IsProcessable(contentType) {
+		return nil, nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image config: %w", err)
+	}
+
+	original, err := StripMetadata(content, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return original, nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	// rotate the pixels so that the variants, which have no metadata, and the
+	// re-encoded original display upright
+	if contentType == "image/jpeg" {
+		if orientation := JPEGOrientation(content); orientation > 1 {
+			img = orient(img, orientation)
+			if original, err = encode(img, contentType); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var variants []Variant
+	for _, size := range variantWidths {
+		bounds := img.Bounds()
+		if size.Width >= bounds.Dx() {
+			continue
+		}
+		height := bounds.Dy() * size.Width / bounds.Dx()
+		resized := image.NewRGBA(image.Rect(0, 0, size.Width, max(height, 1)))
+		draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+
+		encoded, err := encode(resized, contentType)
+		if err != nil {
+			return nil, nil, err
+		}
+		variants = append(variants, Variant{
+			Name:        size.Name,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+			ContentType: encodedContentType(contentType),
+			Content:     encoded,
+		})
+
+		var webp bytes.Buffer
+		if err := nativewebp.Encode(&webp, resized, nil); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode webp variant: %w", err)
+		}
+		variants = append(variants, Variant{
+			Name:        size.Name,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+			ContentType: "image/webp",
+			Content:     webp.Bytes(),
+		})
+	}
+
+	return original, variants, nil
+}
+
+// encode writes img in the format of contentType, GIFs are encoded as PNG
+// since resizing loses the palette and animation
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch encodedContentType(contentType) {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = fmt.Errorf("unsupported content type: %s", contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", contentType, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodedContentType(contentType string) string {
+	if contentType == "image/gif" {
+		return "image/png"
+	}
+	return contentType
+}
+
+// Dimensions returns the width and height of an image, or zero if it cannot
+// be decoded
+func Dimensions(content []byte) (int, int) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
@@ -0,0 +1,285 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width int, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment returns an APP1 segment with a little endian TIFF header whose
+// IFD0 only has the orientation tag
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	ifd := make([]byte, 2+12+4)
+	binary.LittleEndian.PutUint16(ifd[0:], 1)
+	binary.LittleEndian.PutUint16(ifd[2:], 0x0112)
+	binary.LittleEndian.PutUint16(ifd[4:], 3)
+	binary.LittleEndian.PutUint32(ifd[6:], 1)
+	binary.LittleEndian.PutUint16(ifd[10:], orientation)
+	data := append(append(append([]byte{}, exifHeader...), tiff...), ifd...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+func testJPEG(t *testing.T, width int, height int, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(width, height), nil); err != nil {
+		t.Fatal(err)
+	}
+	content := buf.Bytes()
+	// insert the exif segment after SOI
+	return append(append(append([]byte{}, content[:2]...), exifSegment(orientation)...), content[2:]...)
+}
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(width, height)); err != nil {
+		t.Fatal(err)
+	}
+	content := buf.Bytes()
+
+	// insert a tEXt chunk after IHDR
+	data := []byte("Author\x00someone")
+	chunk := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	chunk = append(append(chunk, "tEXt"...), data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	ihdrEnd := len(pngSignature) + 12 + 13
+	return append(append(append([]byte{}, content[:ihdrEnd]...), chunk...), content[ihdrEnd:]...)
+}
+
+func TestStripMetadata(t *testing.T) {
+	for _, test := range []struct {
+		label       string
+		content     []byte
+		contentType string
+		metadata    []byte
+	}{
+		{
+			label:       "JPEG EXIF",
+			content:     testJPEG(t, 16, 8, 1),
+			contentType: "image/jpeg",
+			metadata:    exifHeader,
+		},
+		{
+			label:       "PNG text chunk",
+			content:     testPNG(t, 16, 8),
+			contentType: "image/png",
+			metadata:    []byte("tEXt"),
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			if !bytes.Contains(test.content, test.metadata) {
+				t.Fatalf("expected test image to contain %q", test.metadata)
+			}
+			stripped, err := StripMetadata(test.content, test.contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bytes.Contains(stripped, test.metadata) {
+				t.Errorf("expected %q to be stripped", test.metadata)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(stripped)); err != nil {
+				t.Errorf("expected stripped image to decode, got %v", err)
+			}
+		})
+	}
+}
+
+// withDimensions returns a JPEG whose frame header claims width by height
+// pixels, which is enough for images that are not decoded
+func withDimensions(t *testing.T, content []byte, width int, height int) []byte {
+	sof := bytes.Index(content, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatal("expected test JPEG to have a baseline frame header")
+	}
+	content = append([]byte{}, content...)
+	binary.BigEndian.PutUint16(content[sof+5:], uint16(height))
+	binary.BigEndian.PutUint16(content[sof+7:], uint16(width))
+	return content
+}
+
+func TestStripMetadataKeepsOrientation(t *testing.T) {
+	// an XMP segment is stripped with the rest of the metadata
+	xmp := []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmp = append(binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(xmp)+2)), xmp...)
+	content := testJPEG(t, 16, 8, 6)
+	content = append(append(append([]byte{}, content[:2]...), xmp...), content[2:]...)
+
+	stripped, err := StripMetadata(content, "image/jpeg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if orientation := JPEGOrientation(stripped); orientation != 6 {
+		t.Errorf("expected orientation 6, got %d", orientation)
+	}
+	if bytes.Contains(stripped, []byte("ns.adobe.com")) {
+		t.Errorf("expected XMP to be stripped")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("expected stripped image to decode, got %v", err)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	for _, test := range []struct {
+		label            string
+		content          []byte
+		contentType      string
+		expectedWidth    int
+		expectedHeight   int
+		expectedVariants []string
+		// expectedOrientation is the orientation of a JPEG original, whose
+		// pixels are only rotated when the image is processed
+		expectedOrientation int
+	}{
+		{
+			label:            "Variants smaller than the original",
+			content:          testJPEG(t, 800, 400, 1),
+			contentType:      "image/jpeg",
+			expectedWidth:    800,
+			expectedHeight:   400,
+			expectedVariants: []string{"thumb image/jpeg 320x160", "thumb image/webp 320x160", "w640 image/jpeg 640x320", "w640 image/webp 640x320"},
+		},
+		{
+			label:            "Rotated by EXIF orientation",
+			content:          testJPEG(t, 400, 200, 6),
+			contentType:      "image/jpeg",
+			expectedWidth:    200,
+			expectedHeight:   400,
+			expectedVariants: nil,
+		},
+		{
+			label:               "Images over maxPixels keep their orientation",
+			content:             withDimensions(t, testJPEG(t, 16, 8, 6), 5000, 4000),
+			contentType:         "image/jpeg",
+			expectedWidth:       5000,
+			expectedHeight:      4000,
+			expectedVariants:    nil,
+			expectedOrientation: 6,
+		},
+		{
+			label:            "No variants for small images",
+			content:          testPNG(t, 100, 50),
+			contentType:      "image/png",
+			expectedWidth:    100,
+			expectedHeight:   50,
+			expectedVariants: nil,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			original, variants, err := Process(test.content, test.contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(original))
+			if err != nil {
+				t.Fatalf("expected original to decode, got %v", err)
+			}
+			if config.Width != test.expectedWidth || config.Height != test.expectedHeight {
+				t.Errorf("expected original of %dx%d, got %dx%d", test.expectedWidth, test.expectedHeight, config.Width, config.Height)
+			}
+			if test.contentType == "image/jpeg" && JPEGOrientation(original) != max(test.expectedOrientation, 1) {
+				t.Errorf("expected orientation %d, got %d", max(test.expectedOrientation, 1), JPEGOrientation(original))
+			}
+
+			if len(variants) != len(test.expectedVariants) {
+				t.Fatalf("expected %d variants, got %d", len(test.expectedVariants), len(variants))
+			}
+			for i, variant := range variants {
+				got := fmt.Sprintf("%s %s %dx%d", variant.Name, variant.ContentType, variant.Width, variant.Height)
+				if got != test.expectedVariants[i] {
+					t.Errorf("expected variant %v, got %v", test.expectedVariants[i], got)
+				}
+				if len(variant.Content) == 0 {
+					t.Errorf("expected %s variant to have content", variant.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	for _, test := range []struct {
+		label    string
+		svg      string
+		expected string
+	}{
+		{
+			label:    "Script element",
+			svg:      `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><circle r="4"/></svg>`,
+			expected: `<svg xmlns="http://www.w3.org/2000/svg"><circle r="4"></circle></svg>`,
+		},
+		{
+			label:    "Event handler and javascript link",
+			svg:      `<svg xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)"><a xlink:href="javascript:alert(1)"><use href="#icon"/></a></svg>`,
+			expected: `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a><use href="#icon"></use></a></svg>`,
+		},
+		{
+			label:    "Foreign object and doctype",
+			svg:      `<?xml version="1.0"?><!DOCTYPE svg [<!ENTITY x "y">]><svg><foreignObject><iframe src="https://example.com"/></foreignObject><!-- comment --><text>a &lt; b</text></svg>`,
+			expected: `<?xml version="1.0" encoding="UTF-8"?><svg><text>a &lt; b</text></svg>`,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			sanitized, err := SanitizeSVG([]byte(test.svg))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(sanitized) != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, string(sanitized))
+			}
+		})
+	}
+}
+
+func TestStripMetadataMalformedJPEG(t *testing.T) {
+	for _, test := range []struct {
+		label   string
+		content []byte
+	}{
+		{label: "segment length 0", content: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00, 0xFF, 0xD9}},
+		{label: "segment length 1", content: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x01, 0xFF, 0xD9}},
+		{label: "segment past the end", content: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 0x00}},
+		{label: "truncated marker", content: []byte{0xFF, 0xD8, 0xFF}},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			if orientation := JPEGOrientation(test.content); orientation != 1 {
+				t.Errorf("expected orientation 1, got %d", orientation)
+			}
+			if _, err := StripMetadata(test.content, "image/jpeg"); !errors.Is(err, errMalformed) {
+				t.Errorf("expected %v, got %v", errMalformed, err)
+			}
+		})
+	}
+}
+
+func FuzzStripMetadata(f *testing.F) {
+	f.Add([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00})
+	f.Add([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 0x45, 0x78, 0x69, 0x66, 0x00, 0x00, 0x4D, 0x4D})
+	f.Fuzz(func(t *testing.T, content []byte) {
+		JPEGOrientation(content)
+		StripMetadata(content, "image/jpeg")
+		StripMetadata(content, "image/png")
+	})
+}
@@ -0,0 +1,228 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+)
+
+// png chunks that can carry camera, location or editing metadata
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+var errMalformed = errors.New("malformed image")
+
+// StripMetadata removes EXIF, XMP and IPTC metadata without re-encoding the
+// image. JPEG APP1 and APP13 segments and PNG text and eXIf chunks are
+// dropped, anything needed to render the image such as ICC profiles is kept.
+// The EXIF orientation of a JPEG is kept in an APP1 segment of its own, so
+// the image still displays upright.
+func StripMetadata(content []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(content)
+	case "image/png":
+		return stripPNG(content)
+	default:
+		return content, nil
+	}
+}
+
+func stripJPEG(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != 0xFF || content[1] != 0xD8 {
+		return nil, errMalformed
+	}
+
+	orientation := JPEGOrientation(content)
+	out := bytes.NewBuffer(make([]byte, 0, len(content)))
+	out.Write(content[:2])
+	for i := 2; i < len(content); {
+		if content[i] != 0xFF || i+1 >= len(content) {
+			return nil, errMalformed
+		}
+		marker := content[i+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			out.Write(content[i : i+2])
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// the entropy coded data after start of scan has no metadata
+			out.Write(content[i:])
+			return out.Bytes(), nil
+		}
+
+		if i+4 > len(content) {
+			return nil, errMalformed
+		}
+		// the segment length includes its own two bytes
+		end := i + 2 + int(binary.BigEndian.Uint16(content[i+2:i+4]))
+		if end < i+4 || end > len(content) {
+			return nil, errMalformed
+		}
+		switch {
+		case marker == 0xE1 && orientation > 1 && bytes.HasPrefix(content[i+4:end], exifHeader):
+			// only the first EXIF segment is read for the orientation
+			out.Write(orientationSegment(orientation))
+			orientation = 1
+		case marker != 0xE1 && marker != 0xED:
+			out.Write(content[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
+
+func stripPNG(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, pngSignature) {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(content)))
+	out.Write(pngSignature)
+	for i := len(pngSignature); i < len(content); {
+		if i+8 > len(content) {
+			return nil, errMalformed
+		}
+		// length, type, data and crc
+		end := i + 12 + int(binary.BigEndian.Uint32(content[i:i+4]))
+		if end > len(content) || end < i {
+			return nil, errMalformed
+		}
+		if !pngMetadataChunks[string(content[i+4:i+8])] {
+			out.Write(content[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
+
+// JPEGOrientation returns the EXIF orientation of a JPEG, 1 when it has none
+func JPEGOrientation(content []byte) int {
+	for i := 2; i+4 <= len(content) && content[i] == 0xFF; {
+		marker := content[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(content[i+2:i+4]))
+		if end < i+4 || end > len(content) {
+			break
+		}
+		if marker == 0xE1 && bytes.HasPrefix(content[i+4:end], exifHeader) {
+			return exifOrientation(content[i+4+len(exifHeader) : end])
+		}
+		i = end
+	}
+	return 1
+}
+
+// orientationSegment returns an APP1 segment with a big endian TIFF header
+// whose IFD0 only has the orientation tag
+func orientationSegment(orientation int) []byte {
+	tiff := []byte("MM\x00*\x00\x00\x00\x08")
+	// one entry of type SHORT and count 1, and no next IFD
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(2+len(exifHeader)+len(tiff)))
+	return append(append(segment, exifHeader...), tiff...)
+}
+
+// exifOrientation reads the orientation tag from IFD0 of a TIFF header
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) || ifd < 0 {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient applies an EXIF orientation to img so that it displays upright
+// without the orientation tag
+func orient(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	// orientations 5 to 8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
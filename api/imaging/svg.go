@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// elements that can run scripts or embed other documents, they are removed
+// along with everything inside them
+var unsafeSVGElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+}
+
+// IsSVG reports whether content is an SVG document, allowing for an XML
+// declaration, doctype and comments before the svg element
+func IsSVG(content []byte) bool {
+	head := content[:min(len(content), 1024)]
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimSpace(head)
+	if !bytes.HasPrefix(head, []byte("<")) {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(head), []byte("<svg"))
+}
+
+// SanitizeSVG rewrites an SVG without scripts, event handler attributes,
+// javascript or external links and doctypes, so it is safe to serve as an
+// image. Documents that are not well formed XML are rejected.
+func SanitizeSVG(content []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	// RawToken does not check that elements are closed, so track them here
+	var open []xml.Name
+	skipDepth := 0
+	hasSVG := false
+	for {
+		// namespace prefixes are kept as is, RawToken does not resolve them
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse svg: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+			if skipDepth > 0 || unsafeSVGElements[strings.ToLower(t.Name.Local)] {
+				skipDepth++
+				continue
+			}
+			if strings.EqualFold(t.Name.Local, "svg") {
+				hasSVG = true
+			}
+			out.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				if !safeSVGAttr(attr) {
+					continue
+				}
+				out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				if err := xml.EscapeText(&out, []byte(attr.Value)); err != nil {
+					return nil, err
+				}
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, fmt.Errorf("failed to parse svg: unexpected end element %s", qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 {
+				continue
+			}
+			if err := xml.EscapeText(&out, t); err != nil {
+				return nil, err
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" && skipDepth == 0 {
+				out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+			}
+		}
+		// comments and directives such as doctypes with entities are dropped
+	}
+
+	if len(open) > 0 {
+		return nil, fmt.Errorf("failed to parse svg: element %s is not closed", qualifiedName(open[len(open)-1]))
+	}
+	if !hasSVG {
+		return nil, errors.New("no svg element found")
+	}
+	return out.Bytes(), nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// safeSVGAttr rejects event handlers and attributes that link to or run
+// anything other than fragments in the document, web URLs or raster images
+func safeSVGAttr(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+
+	value := strings.ToLower(strings.Join(strings.Fields(attr.Value), ""))
+	if strings.Contains(value, "javascript:") || strings.Contains(value, "vbscript:") {
+		return false
+	}
+	if local != "href" && local != "src" {
+		return true
+	}
+	for _, prefix := range []string{"#", "http://", "https://", "data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
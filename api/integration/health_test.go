@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/service"
+)
+
+func TestHealthApi(t *testing.T) {
+	integrationTest(t)
+
+	for _, test := range []struct {
+		label        string
+		route        string
+		dependencies []string
+	}{
+		{
+			label: "Get Health",
+			route: "/api/v1/health",
+		},
+		{
+			label:        "Get Ready",
+			route:        "/api/v1/ready",
+			dependencies: []string{"dynamodb", "s3"},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			// act
+			res, err := http.Get("http://127.0.0.1:3000" + test.route)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("error in reading body: %v", err)
+			}
+			if res.StatusCode != http.StatusOK {
+				t.Logf("Test request %v: response: %v", test.label, string(body))
+				t.Fatalf("error status code: %v", res.StatusCode)
+			}
+
+			// assert
+			var health service.HealthResponse
+			if err := json.Unmarshal(body, &health); err != nil {
+				t.Fatalf("error in unmarshal: %v", err)
+			}
+			if health.Status != "ok" {
+				t.Errorf("expected status ok, got %v", health.Status)
+			}
+			for _, dependency := range test.dependencies {
+				if health.Dependencies[dependency].Status != "ok" {
+					t.Errorf("expected %s status ok, got %v", dependency, health.Dependencies[dependency])
+				}
+			}
+		})
+	}
+}
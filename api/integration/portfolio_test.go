@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestPortfolioApi(t *testing.T) {
+	integrationTest(t)
+
+	// act
+	res, err := http.Get("http://127.0.0.1:3000/api/v1/portfolio")
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("error in reading body: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Logf("Test request Get Portfolio: response: %v", string(body))
+		t.Fatalf("error status code: %v", res.StatusCode)
+	}
+
+	// assert
+	var portfolio models.Portfolio
+	if err := json.Unmarshal(body, &portfolio); err != nil {
+		t.Fatalf("error in unmarshal: %v", err)
+	}
+	if len(portfolio.Errors) != 0 {
+		t.Errorf("expected no section errors, got %v", portfolio.Errors)
+	}
+	if portfolio.Work == nil || portfolio.SkillsTools == nil || portfolio.Projects == nil {
+		t.Errorf("expected all sections to be present, got %v", portfolio)
+	}
+}
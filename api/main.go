@@ -7,5 +7,5 @@ import (
 
 func main() {
 	srv := service.NewService()
-	lambda.Start(srv.HandleRoute)
+	lambda.Start(srv.HandleEvent)
 }
@@ -0,0 +1,121 @@
+// Package mediatype identifies media files by their content, so files are
+// checked the same way however they reach the bucket: multipart and
+// presigned uploads, archive imports and pwctl.
+package mediatype
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+)
+
+// ErrUnsupported is wrapped by the errors of files that cannot be identified
+// or are not allowed
+var ErrUnsupported = errors.New("unsupported media type")
+
+// Detectable are the content types Detect can identify from magic bytes
+var Detectable = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"image/svg+xml",
+	"image/avif",
+	"video/mp4",
+	"video/webm",
+	"application/pdf",
+}
+
+// SniffLength is how much of a file Detect needs, imaging.IsSVG looks at the
+// most
+const SniffLength = 1024
+
+// ParseAllowed parses a comma separated allowlist of content types, such as
+// ALLOWED_CONTENT_TYPES. An empty list allows every detectable type.
+func ParseAllowed(contentTypes string) ([]string, error) {
+	if contentTypes == "" {
+		return Detectable, nil
+	}
+	var allowed []string
+	for _, contentType := range strings.Split(contentTypes, ",") {
+		contentType = strings.ToLower(strings.TrimSpace(contentType))
+		if !slices.Contains(Detectable, contentType) {
+			return nil, fmt.Errorf("%s is not one of %v", contentType, Detectable)
+		}
+		allowed = append(allowed, contentType)
+	}
+	return allowed, nil
+}
+
+// Prepare identifies the content type of content, which must be in allowed,
+// and returns the content to store. SVGs are sanitized of scripts and event
+// handlers. Errors for files that cannot be stored wrap ErrUnsupported.
+func Prepare(content []byte, allowed []string) (string, []byte, error) {
+	contentType, err := Detect(content)
+	if err != nil {
+		return "", nil, err
+	}
+	if !slices.Contains(allowed, contentType) {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsupported, contentType)
+	}
+	if contentType == "image/svg+xml" {
+		if content, err = imaging.SanitizeSVG(content); err != nil {
+			return "", nil, fmt.Errorf("%w: not a valid svg: %v", ErrUnsupported, err)
+		}
+	}
+	return contentType, content, nil
+}
+
+// Detect identifies the content type of a file from its magic bytes. Types
+// that cannot be identified return ErrUnsupported.
+func Detect(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg", nil
+	case bytes.HasPrefix(data, []byte{0x89, 0x50, 0x4E, 0x47}):
+		return "image/png", nil
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "image/gif", nil
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		return "image/webp", nil
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return detectEBML(data)
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return "application/pdf", nil
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return detectISOBaseMedia(data)
+	case imaging.IsSVG(data):
+		return "image/svg+xml", nil
+	default:
+		return "", fmt.Errorf("%w: unknown content type", ErrUnsupported)
+	}
+}
+
+// detectEBML identifies WebM videos from the DocType in the EBML header.
+// Other Matroska files are not supported.
+func detectEBML(data []byte) (string, error) {
+	header := data[:min(len(data), 64)]
+	// DocType element ID followed by a one byte size
+	if i := bytes.Index(header, []byte{0x42, 0x82}); i != -1 && i+3 <= len(header) && bytes.HasPrefix(header[i+3:], []byte("webm")) {
+		return "video/webm", nil
+	}
+	return "", fmt.Errorf("%w: unknown EBML document type", ErrUnsupported)
+}
+
+// detectISOBaseMedia identifies AVIF images and MP4 videos from the major
+// brand of the ftyp box. Other ISO base media files such as HEIC and
+// QuickTime are not supported.
+func detectISOBaseMedia(data []byte) (string, error) {
+	switch brand := string(data[8:12]); brand {
+	case "avif", "avis":
+		return "image/avif", nil
+	case "isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "M4V ", "dash", "MSNV":
+		return "video/mp4", nil
+	default:
+		return "", fmt.Errorf("%w: unknown ftyp brand %q", ErrUnsupported, brand)
+	}
+}
@@ -0,0 +1,100 @@
+package mediatype
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		label               string
+		data                []byte
+		expectedContentType string
+	}{
+		{label: "JPEG", data: []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), expectedContentType: "image/jpeg"},
+		{label: "PNG", data: []byte("\x89PNG\r\n\x1a\n"), expectedContentType: "image/png"},
+		{label: "GIF", data: []byte("GIF89a"), expectedContentType: "image/gif"},
+		{label: "WebP", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8L"), expectedContentType: "image/webp"},
+		{label: "AVIF", data: []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"), expectedContentType: "image/avif"},
+		{label: "MP4", data: []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), expectedContentType: "video/mp4"},
+		{label: "WebM", data: []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\xf7\x81\x01\x42\xf2\x81\x04\x42\xf3\x81\x08\x42\x82\x84webm"), expectedContentType: "video/webm"},
+		{label: "Matroska", data: []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x88matroska"), expectedContentType: ""},
+		{label: "PDF", data: []byte("%PDF-1.7\n"), expectedContentType: "application/pdf"},
+		{label: "SVG", data: []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`), expectedContentType: "image/svg+xml"},
+		{label: "HEIC", data: []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), expectedContentType: ""},
+		{label: "Short file", data: []byte("GIF"), expectedContentType: ""},
+		{label: "Text", data: []byte("hello world"), expectedContentType: ""},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			contentType, err := Detect(test.data)
+			if test.expectedContentType == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Errorf("expected ErrUnsupported, got %v (%s)", err, contentType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contentType != test.expectedContentType {
+				t.Errorf("expected %v, got %v", test.expectedContentType, contentType)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	for _, test := range []struct {
+		label               string
+		data                []byte
+		allowed             []string
+		expectedContentType string
+		expectedContent     string
+	}{
+		{label: "Allowed", data: []byte("%PDF-1.7\n"), allowed: Detectable, expectedContentType: "application/pdf", expectedContent: "%PDF-1.7\n"},
+		{label: "Not allowed", data: []byte("%PDF-1.7\n"), allowed: []string{"image/png"}},
+		{label: "Not detectable", data: []byte("hello world"), allowed: Detectable},
+		{label: "SVG is sanitized", data: []byte(`<svg><script>alert(1)</script></svg>`), allowed: Detectable, expectedContentType: "image/svg+xml", expectedContent: "<svg></svg>"},
+		{label: "Invalid SVG", data: []byte(`<svg><g></svg>`), allowed: Detectable},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			contentType, content, err := Prepare(test.data, test.allowed)
+			if test.expectedContentType == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Errorf("expected ErrUnsupported, got %v (%s)", err, contentType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contentType != test.expectedContentType || string(content) != test.expectedContent {
+				t.Errorf("expected %s %q, got %s %q", test.expectedContentType, test.expectedContent, contentType, content)
+			}
+		})
+	}
+}
+
+func TestParseAllowed(t *testing.T) {
+	for _, test := range []struct {
+		label           string
+		contentTypes    string
+		expectedAllowed []string
+		expectedErr     bool
+	}{
+		{label: "Default", contentTypes: "", expectedAllowed: Detectable},
+		{label: "List", contentTypes: "image/png, Image/JPEG", expectedAllowed: []string{"image/png", "image/jpeg"}},
+		{label: "Not detectable", contentTypes: "image/png,text/html", expectedErr: true},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			allowed, err := ParseAllowed(test.contentTypes)
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			if !reflect.DeepEqual(allowed, test.expectedAllowed) {
+				t.Errorf("expected %v, got %v", test.expectedAllowed, allowed)
+			}
+		})
+	}
+}
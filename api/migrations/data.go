@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// RenameAttribute returns a migration that renames the attribute from to to
+// on the items of partitions. Items that already have to and not from are
+// unchanged, and items that have both are an error.
+func RenameAttribute(id string, from string, to string, partitions ...string) Migration {
+	return Migration{
+		ID:          id,
+		Description: fmt.Sprintf("rename %s to %s", from, to),
+		Partitions:  partitions,
+		Apply: func(ctx context.Context, item map[string]types.AttributeValue) (bool, error) {
+			value, ok := item[from]
+			if !ok {
+				return false, nil
+			}
+			if _, ok := item[to]; ok {
+				return false, fmt.Errorf("item has both %s and %s", from, to)
+			}
+			item[to] = value
+			delete(item, from)
+			return true, nil
+		},
+	}
+}
+
+// ConvertDates returns a migration that rewrites the string attributes of the
+// items of partitions with layout, e.g. "2006-01" to write Jan 2024 as
+// 2024-01. Dates are read in the layouts models.ParseDate knows, and dates it
+// does not, such as Present, are unchanged.
+func ConvertDates(id string, layout string, attributes []string, partitions ...string) Migration {
+	return Migration{
+		ID:          id,
+		Description: fmt.Sprintf("convert %v to %s", attributes, layout),
+		Partitions:  partitions,
+		Apply: func(ctx context.Context, item map[string]types.AttributeValue) (bool, error) {
+			changed := false
+			for _, attribute := range attributes {
+				value, ok := item[attribute].(*types.AttributeValueMemberS)
+				if !ok {
+					continue
+				}
+				date, _, ok := models.ParseDate(value.Value)
+				if !ok {
+					continue
+				}
+				if converted := date.Format(layout); converted != value.Value {
+					item[attribute] = &types.AttributeValueMemberS{Value: converted}
+					changed = true
+				}
+			}
+			return changed, nil
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// MediaStore is the bucket project media is stored in. The bucket implements
+// it.
+type MediaStore interface {
+	ParseMediaLink(mediaLink string) *models.MediaRef
+	GetFileInfo(ctx context.Context, key string) (*s3.HeadObjectOutput, error)
+}
+
+// MediaRefs returns a migration that replaces the mediaLinks of projects
+// stored before media references with references to the same media. S3
+// objects get the content type and size of the object, and objects that do
+// not exist are logged and referenced without them. Projects that only store
+// references are unchanged, as are attributes that are not media.
+func MediaRefs(id string, media MediaStore) Migration {
+	return Migration{
+		ID:          id,
+		Description: "replace the mediaLinks of projects with media references",
+		Partitions:  []string{"Projects"},
+		Apply: func(ctx context.Context, item map[string]types.AttributeValue) (bool, error) {
+			var project models.Project
+			if err := attributevalue.UnmarshalMap(item, &project); err != nil {
+				return false, fmt.Errorf("invalid project: %w", err)
+			}
+			before, err := attributevalue.MarshalMap(project)
+			if err != nil {
+				return false, err
+			}
+			if !project.SetMediaRefs(media.ParseMediaLink) {
+				return false, nil
+			}
+
+			for _, ref := range project.MediaRefs() {
+				if !ref.IsS3() || ref.Size > 0 {
+					continue
+				}
+				info, err := media.GetFileInfo(ctx, ref.Key)
+				if err != nil {
+					return false, fmt.Errorf("failed to get %s: %w", ref.Key, err)
+				}
+				if info == nil {
+					log.Printf("media %s of project %s does not exist", ref.Key, project.SortValue)
+					continue
+				}
+				ref.ContentType = aws.ToString(info.ContentType)
+				ref.Size = aws.ToInt64(info.ContentLength)
+			}
+
+			after, err := attributevalue.MarshalMap(project)
+			if err != nil {
+				return false, err
+			}
+			for name := range before {
+				if _, ok := after[name]; !ok {
+					delete(item, name)
+				}
+			}
+			for name, value := range after {
+				item[name] = value
+			}
+			return true, nil
+		},
+	}
+}
@@ -0,0 +1,315 @@
+// Package migrations creates the table and its global secondary indexes, and
+// runs data migrations on its items. Applied migrations are recorded in the
+// table under the Migrations partition, so running them again only runs the
+// new ones. A migration that fails is resumed after the last items it wrote.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+)
+
+// PartitionKey is the personalWebsiteType of the records of applied migrations
+const PartitionKey = "Migrations"
+
+const (
+	StatusRunning = "running"
+	StatusApplied = "applied"
+	// StatusSkipped is reported for migrations that were already applied
+	StatusSkipped = "skipped"
+	// StatusPending is reported for migrations a dry run would apply
+	StatusPending = "pending"
+
+	// how many changed items are written between checkpoints
+	checkpointSize = 25
+)
+
+// ErrKeyChanged is returned when a migration changes the key of an item,
+// which would write a copy of the item instead of updating it
+var ErrKeyChanged = errors.New("migration changed the key of an item")
+
+// now returns the time migrations are recorded at
+var now = time.Now
+
+// All returns the migrations of the table in the order they run, with the
+// bucket media is stored in. Append new migrations to the end, and never
+// change the ID of a migration that may have been applied.
+func All(media MediaStore) []Migration {
+	return []Migration{
+		MediaRefs("001-media-refs", media),
+	}
+}
+
+// Migration updates the items of Partitions, or every partition of
+// database.PartitionKeys when empty. Apply updates item in place and returns
+// whether it changed it. It must not change the key of the item, and should
+// leave items it already migrated unchanged so a resumed migration is safe.
+type Migration struct {
+	ID          string
+	Description string
+	Partitions  []string
+	Apply       func(ctx context.Context, item map[string]types.AttributeValue) (bool, error)
+}
+
+type Options struct {
+	// DryRun reports what would be created and migrated without writing to
+	// the table
+	DryRun bool
+	// SkipTable does not create the table or its indexes, e.g. for tables
+	// managed by CloudFormation
+	SkipTable bool
+}
+
+type Report struct {
+	Table      *TableReport      `json:"table,omitempty"`
+	Migrations []MigrationReport `json:"migrations"`
+}
+
+type MigrationReport struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Resumed is set when the migration continued after a checkpoint
+	Resumed bool `json:"resumed,omitempty"`
+	Scanned int  `json:"scanned"`
+	Changed int  `json:"changed"`
+	// ChangedItems are the items changed, or that would be on a dry run
+	ChangedItems []ItemKey `json:"changedItems"`
+}
+
+type ItemKey struct {
+	PersonalWebsiteType string `json:"personalWebsiteType" dynamodbav:"personalWebsiteType"`
+	SortValue           string `json:"sortValue" dynamodbav:"sortValue"`
+}
+
+// record is the item of a migration in the Migrations partition
+type record struct {
+	PersonalWebsiteType string `dynamodbav:"personalWebsiteType"`
+	SortValue           string `dynamodbav:"sortValue"`
+	Description         string `dynamodbav:"description"`
+	Status              string `dynamodbav:"status"`
+	// Checkpoint is the last item of a running migration whose change is
+	// written
+	Checkpoint *ItemKey  `dynamodbav:"checkpoint,omitempty"`
+	Changed    int       `dynamodbav:"changed"`
+	StartedAt  time.Time `dynamodbav:"startedAt"`
+	AppliedAt  time.Time `dynamodbav:"appliedAt,omitempty"`
+}
+
+// Run ensures the table exists with its indexes, then applies the migrations
+// that have not been applied in order. Each migration writes its changed items
+// in batches and records a checkpoint after each, which a failed migration is
+// resumed from when run again.
+func Run(ctx context.Context, svc TableAPI, tableName string, migrations []Migration, opts Options) (Report, error) {
+	report := Report{Migrations: make([]MigrationReport, 0, len(migrations))}
+
+	ids := make(map[string]bool)
+	for _, migration := range migrations {
+		if migration.ID == "" || ids[migration.ID] {
+			return report, fmt.Errorf("migration IDs must be unique and not empty, got %q", migration.ID)
+		}
+		ids[migration.ID] = true
+	}
+
+	if !opts.SkipTable {
+		tableReport, err := EnsureTable(ctx, svc, tableName, opts.DryRun)
+		report.Table = &tableReport
+		if err != nil {
+			return report, err
+		}
+		// a dry run does not create the table, so there is nothing to scan
+		if tableReport.Created && opts.DryRun {
+			for _, migration := range migrations {
+				report.Migrations = append(report.Migrations, MigrationReport{
+					ID:           migration.ID,
+					Status:       StatusPending,
+					ChangedItems: make([]ItemKey, 0),
+				})
+			}
+			return report, nil
+		}
+	}
+
+	records, err := getRecords(ctx, svc, tableName)
+	if err != nil {
+		return report, err
+	}
+
+	for _, migration := range migrations {
+		migrationReport, err := run(ctx, svc, tableName, migration, records[migration.ID], opts.DryRun)
+		report.Migrations = append(report.Migrations, migrationReport)
+		if err != nil {
+			return report, fmt.Errorf("failed to run migration %s: %w", migration.ID, err)
+		}
+	}
+	return report, nil
+}
+
+func run(ctx context.Context, svc TableAPI, tableName string, migration Migration, rec *record, dryRun bool) (MigrationReport, error) {
+	report := MigrationReport{ID: migration.ID, ChangedItems: make([]ItemKey, 0)}
+	if rec != nil && rec.Status == StatusApplied {
+		report.Status = StatusSkipped
+		return report, nil
+	}
+
+	report.Status = StatusPending
+	if rec == nil {
+		rec = &record{
+			PersonalWebsiteType: PartitionKey,
+			SortValue:           migration.ID,
+			Description:         migration.Description,
+			Status:              StatusRunning,
+			StartedAt:           now().UTC(),
+		}
+	}
+	checkpoint := rec.Checkpoint
+	report.Resumed = checkpoint != nil
+	if !dryRun {
+		if err := putRecord(ctx, svc, tableName, rec); err != nil {
+			return report, err
+		}
+	}
+
+	partitions := migration.Partitions
+	if len(partitions) == 0 {
+		partitions = database.PartitionKeys
+	}
+
+	pending := make([]map[string]types.AttributeValue, 0, checkpointSize)
+	var last ItemKey
+	flush := func() error {
+		if dryRun {
+			pending = pending[:0]
+			return nil
+		}
+		if len(pending) == 0 && (last == ItemKey{} || rec.Checkpoint != nil && *rec.Checkpoint == last) {
+			return nil
+		}
+		if err := database.BatchPutItems(ctx, svc, tableName, pending); err != nil {
+			return err
+		}
+		rec.Changed += len(pending)
+		pending = pending[:0]
+		checkpoint := last
+		rec.Checkpoint = &checkpoint
+		return putRecord(ctx, svc, tableName, rec)
+	}
+
+	// partitions before the checkpoint are done
+	resuming := checkpoint != nil && slices.Contains(partitions, checkpoint.PersonalWebsiteType)
+	for _, partition := range partitions {
+		if resuming && partition != checkpoint.PersonalWebsiteType {
+			continue
+		}
+
+		items, err := database.QueryItems(ctx, svc, tableName, partition)
+		if err != nil {
+			return report, fmt.Errorf("failed to query %s: %w", partition, err)
+		}
+		for _, item := range items {
+			key, err := itemKey(item)
+			if err != nil {
+				return report, err
+			}
+			// items are sorted by sortValue, so items up to the checkpoint are done
+			if resuming {
+				if key.SortValue <= checkpoint.SortValue {
+					continue
+				}
+				resuming = false
+			}
+
+			report.Scanned++
+			changed, err := migration.Apply(ctx, item)
+			if err != nil {
+				return report, fmt.Errorf("failed to migrate %s %s: %w", key.PersonalWebsiteType, key.SortValue, err)
+			}
+			if migratedKey, err := itemKey(item); err != nil || migratedKey != key {
+				return report, fmt.Errorf("%w: %s %s", ErrKeyChanged, key.PersonalWebsiteType, key.SortValue)
+			}
+			last = key
+			if !changed {
+				continue
+			}
+
+			report.Changed++
+			report.ChangedItems = append(report.ChangedItems, key)
+			pending = append(pending, item)
+			if len(pending) == checkpointSize {
+				if err := flush(); err != nil {
+					return report, err
+				}
+			}
+		}
+		// the checkpoint was the last item of its partition
+		resuming = false
+
+		if err := flush(); err != nil {
+			return report, err
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	rec.Status = StatusApplied
+	rec.Checkpoint = nil
+	rec.AppliedAt = now().UTC()
+	if err := putRecord(ctx, svc, tableName, rec); err != nil {
+		return report, err
+	}
+	report.Status = StatusApplied
+	return report, nil
+}
+
+// getRecords returns the records of the migrations that were started by ID
+func getRecords(ctx context.Context, svc TableAPI, tableName string) (map[string]*record, error) {
+	items, err := database.QueryItems(ctx, svc, tableName, PartitionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", PartitionKey, err)
+	}
+
+	records := make(map[string]*record, len(items))
+	for _, item := range items {
+		var rec record
+		if err := attributevalue.UnmarshalMap(item, &rec); err != nil {
+			return nil, fmt.Errorf("invalid migration record: %w", err)
+		}
+		records[rec.SortValue] = &rec
+	}
+	return records, nil
+}
+
+func putRecord(ctx context.Context, svc TableAPI, tableName string, rec *record) error {
+	item, err := attributevalue.MarshalMap(rec)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", rec.SortValue, err)
+	}
+	return nil
+}
+
+func itemKey(item map[string]types.AttributeValue) (ItemKey, error) {
+	var key ItemKey
+	if err := attributevalue.UnmarshalMap(item, &key); err != nil {
+		return key, fmt.Errorf("invalid item key: %w", err)
+	}
+	if key.PersonalWebsiteType == "" || key.SortValue == "" {
+		return key, fmt.Errorf("invalid item key: %+v", key)
+	}
+	return key, nil
+}
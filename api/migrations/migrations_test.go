@@ -0,0 +1,507 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// fakeTable stores items by personalWebsiteType and sortValue. Tables and
+// indexes are active the first time they are described after being created.
+type fakeTable struct {
+	description *types.TableDescription
+	items       map[ItemKey]map[string]types.AttributeValue
+	creates     int
+	updates     int
+	// failWritesAfter fails BatchWriteItem after that many writes when set
+	failWritesAfter int
+	writes          int
+}
+
+func newFakeTable() *fakeTable {
+	return &fakeTable{items: make(map[ItemKey]map[string]types.AttributeValue)}
+}
+
+func (f *fakeTable) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if f.description == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	description := *f.description
+	f.description.TableStatus = types.TableStatusActive
+	for i := range f.description.GlobalSecondaryIndexes {
+		f.description.GlobalSecondaryIndexes[i].IndexStatus = types.IndexStatusActive
+	}
+	return &dynamodb.DescribeTableOutput{Table: &description}, nil
+}
+
+func (f *fakeTable) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	f.creates++
+	f.description = &types.TableDescription{
+		TableName:   params.TableName,
+		TableStatus: types.TableStatusCreating,
+		KeySchema:   params.KeySchema,
+	}
+	for _, index := range params.GlobalSecondaryIndexes {
+		f.description.GlobalSecondaryIndexes = append(f.description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   index.IndexName,
+			IndexStatus: types.IndexStatusCreating,
+		})
+	}
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (f *fakeTable) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	f.updates++
+	if len(params.GlobalSecondaryIndexUpdates) != 1 {
+		return nil, errors.New("only one index can be created per update")
+	}
+	create := params.GlobalSecondaryIndexUpdates[0].Create
+	if f.description.BillingModeSummary != nil && create.ProvisionedThroughput != nil {
+		return nil, errors.New("on demand indexes have no provisioned throughput")
+	}
+	f.description.TableStatus = types.TableStatusUpdating
+	f.description.GlobalSecondaryIndexes = append(f.description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+		IndexName:   create.IndexName,
+		IndexStatus: types.IndexStatusCreating,
+	})
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (f *fakeTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	partitionKey := params.ExpressionAttributeValues[":partitionKey"].(*types.AttributeValueMemberS).Value
+	var keys []ItemKey
+	for key := range f.items {
+		if key.PersonalWebsiteType == partitionKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].SortValue < keys[j].SortValue })
+
+	output := &dynamodb.QueryOutput{}
+	for _, key := range keys {
+		output.Items = append(output.Items, copyItem(f.items[key]))
+	}
+	return output, nil
+}
+
+func (f *fakeTable) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.put(params.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeTable) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if f.failWritesAfter > 0 && f.writes >= f.failWritesAfter {
+		return nil, errors.New("throughput exceeded")
+	}
+	for _, requests := range params.RequestItems {
+		for _, request := range requests {
+			f.put(request.PutRequest.Item)
+			f.writes++
+		}
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+func (f *fakeTable) put(item map[string]types.AttributeValue) {
+	key, _ := itemKey(item)
+	f.items[key] = copyItem(item)
+}
+
+func (f *fakeTable) record(id string) *record {
+	records, _ := getRecords(context.Background(), f, "table")
+	return records[id]
+}
+
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	copied := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		copied[name] = value
+	}
+	return copied
+}
+
+func workItem(sortValue string, startDate string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"personalWebsiteType": &types.AttributeValueMemberS{Value: "Work"},
+		"sortValue":           &types.AttributeValueMemberS{Value: sortValue},
+		"startDate":           &types.AttributeValueMemberS{Value: startDate},
+		"endDate":             &types.AttributeValueMemberS{Value: "Present"},
+	}
+}
+
+func activeTable(indexes ...string) *types.TableDescription {
+	description := &types.TableDescription{
+		TableStatus: types.TableStatusActive,
+		KeySchema:   keySchema(),
+	}
+	for _, index := range indexes {
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   aws.String(index),
+			IndexStatus: types.IndexStatusActive,
+		})
+	}
+	return description
+}
+
+func TestEnsureTable(t *testing.T) {
+	pollInterval = time.Millisecond
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		label           string
+		description     *types.TableDescription
+		dryRun          bool
+		expectedReport  TableReport
+		expectedCreates int
+		expectedUpdates int
+		expectedError   error
+	}{
+		{
+			label:           "Create table",
+			expectedReport:  TableReport{Created: true, CreatedIndexes: []string{StartDateIndex}},
+			expectedCreates: 1,
+		},
+		{
+			label:          "Create table dry run",
+			dryRun:         true,
+			expectedReport: TableReport{Created: true, CreatedIndexes: []string{StartDateIndex}},
+		},
+		{
+			label:          "Existing table",
+			description:    activeTable(StartDateIndex),
+			expectedReport: TableReport{CreatedIndexes: []string{}},
+		},
+		{
+			label:           "Missing index",
+			description:     activeTable(),
+			expectedReport:  TableReport{CreatedIndexes: []string{StartDateIndex}},
+			expectedUpdates: 1,
+		},
+		{
+			label: "Missing index of on demand table",
+			description: func() *types.TableDescription {
+				description := activeTable()
+				description.BillingModeSummary = &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest}
+				return description
+			}(),
+			expectedReport:  TableReport{CreatedIndexes: []string{StartDateIndex}},
+			expectedUpdates: 1,
+		},
+		{
+			label: "Different keys",
+			description: &types.TableDescription{
+				TableStatus: types.TableStatusActive,
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("name"), KeyType: types.KeyTypeRange},
+				},
+			},
+			expectedReport: TableReport{CreatedIndexes: []string{}},
+			expectedError:  ErrSchemaMismatch,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			table := newFakeTable()
+			table.description = test.description
+
+			report, err := EnsureTable(ctx, table, "table", test.dryRun)
+			if !errors.Is(err, test.expectedError) {
+				t.Fatalf("expected error %v, got %v", test.expectedError, err)
+			}
+			if !reflect.DeepEqual(report, test.expectedReport) {
+				t.Errorf("expected %+v, got %+v", test.expectedReport, report)
+			}
+			if table.creates != test.expectedCreates || table.updates != test.expectedUpdates {
+				t.Errorf("expected %d creates and %d updates, got %d and %d", test.expectedCreates, test.expectedUpdates, table.creates, table.updates)
+			}
+			if test.expectedError == nil && !test.dryRun && !tableActive(table.description) {
+				t.Errorf("expected the table to be active, got %+v", table.description)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	pollInterval = time.Millisecond
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	ctx := context.Background()
+
+	table := newFakeTable()
+	table.put(workItem("2019-06-11", "Jun 2019"))
+	table.put(workItem("2021-01-04", "2021-01"))
+	migrations := []Migration{
+		ConvertDates("001-iso-dates", "2006-01-02", []string{"startDate", "endDate"}, "Work"),
+		RenameAttribute("002-rename-start-date", "startDate", "start", "Work"),
+	}
+
+	report, err := Run(ctx, table, "table", migrations, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Table.Created || report.Migrations[0].Status != StatusPending || report.Migrations[0].Scanned != 0 {
+		t.Errorf("expected a dry run of a new table to only report it, got %+v", report)
+	}
+	if table.creates != 0 {
+		t.Errorf("expected the dry run not to create the table")
+	}
+
+	table.description = activeTable(StartDateIndex)
+	report, err = Run(ctx, table, "table", migrations, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MigrationReport{
+		{
+			ID:      "001-iso-dates",
+			Status:  StatusPending,
+			Scanned: 2,
+			Changed: 2,
+			ChangedItems: []ItemKey{
+				{PersonalWebsiteType: "Work", SortValue: "2019-06-11"},
+				{PersonalWebsiteType: "Work", SortValue: "2021-01-04"},
+			},
+		},
+		{
+			ID:      "002-rename-start-date",
+			Status:  StatusPending,
+			Scanned: 2,
+			Changed: 2,
+			ChangedItems: []ItemKey{
+				{PersonalWebsiteType: "Work", SortValue: "2019-06-11"},
+				{PersonalWebsiteType: "Work", SortValue: "2021-01-04"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(report.Migrations, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report.Migrations)
+	}
+	if len(table.items) != 2 || table.writes != 0 {
+		t.Errorf("expected the dry run not to write, got %d writes", table.writes)
+	}
+
+	report, err = Run(ctx, table, "table", migrations, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected[0].Status = StatusApplied
+	expected[1].Status = StatusApplied
+	if !reflect.DeepEqual(report.Migrations, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report.Migrations)
+	}
+	migrated := table.items[ItemKey{PersonalWebsiteType: "Work", SortValue: "2019-06-11"}]
+	if migrated["start"].(*types.AttributeValueMemberS).Value != "2019-06-01" || migrated["startDate"] != nil {
+		t.Errorf("unexpected migrated item %+v", migrated)
+	}
+	if migrated["endDate"].(*types.AttributeValueMemberS).Value != "Present" {
+		t.Errorf("expected Present to be unchanged, got %+v", migrated["endDate"])
+	}
+	rec := table.record("001-iso-dates")
+	if rec.Status != StatusApplied || rec.Changed != 2 || rec.Checkpoint != nil || !rec.AppliedAt.Equal(now()) {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	writes := table.writes
+	report, err = Run(ctx, table, "table", migrations, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range report.Migrations {
+		if migration.Status != StatusSkipped {
+			t.Errorf("expected applied migrations to be skipped, got %+v", migration)
+		}
+	}
+	if table.writes != writes {
+		t.Errorf("expected applied migrations not to write")
+	}
+}
+
+func TestRunResumes(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	table.description = activeTable(StartDateIndex)
+	for i := 0; i < 60; i++ {
+		table.put(workItem(fmt.Sprintf("2020-01-%02d", i), "2020"))
+	}
+	migration := RenameAttribute("001-rename", "startDate", "start")
+
+	// the third batch fails after two batches of 25 items are written
+	table.failWritesAfter = 2 * checkpointSize
+	report, err := Run(ctx, table, "table", []Migration{migration}, Options{SkipTable: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if report.Table != nil {
+		t.Errorf("expected the table to be skipped, got %+v", report.Table)
+	}
+	rec := table.record("001-rename")
+	expectedCheckpoint := &ItemKey{PersonalWebsiteType: "Work", SortValue: "2020-01-49"}
+	if rec.Status != StatusRunning || rec.Changed != 50 || !reflect.DeepEqual(rec.Checkpoint, expectedCheckpoint) {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	table.failWritesAfter = 0
+	report, err = Run(ctx, table, "table", []Migration{migration}, Options{SkipTable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrationReport := report.Migrations[0]; !migrationReport.Resumed || migrationReport.Scanned != 10 || migrationReport.Changed != 10 || migrationReport.Status != StatusApplied {
+		t.Errorf("expected the migration to resume after the checkpoint, got %+v", migrationReport)
+	}
+	if rec := table.record("001-rename"); rec.Changed != 60 || rec.Status != StatusApplied {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	for key, item := range table.items {
+		if key.PersonalWebsiteType == "Work" && (item["start"] == nil || item["startDate"] != nil) {
+			t.Errorf("expected %s to be migrated", key.SortValue)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	ctx := context.Background()
+	changeKey := Migration{
+		ID: "001-change-key",
+		Apply: func(ctx context.Context, item map[string]types.AttributeValue) (bool, error) {
+			item["sortValue"] = &types.AttributeValueMemberS{Value: "changed"}
+			return true, nil
+		},
+	}
+	both := workItem("2020-01-01", "2020")
+	both["start"] = &types.AttributeValueMemberS{Value: "2020"}
+
+	for _, test := range []struct {
+		label      string
+		migrations []Migration
+		items      []map[string]types.AttributeValue
+		expected   error
+	}{
+		{
+			label:      "Changed key",
+			migrations: []Migration{changeKey},
+			items:      []map[string]types.AttributeValue{workItem("2020-01-01", "2020")},
+			expected:   ErrKeyChanged,
+		},
+		{
+			label:      "Rename to an existing attribute",
+			migrations: []Migration{RenameAttribute("001-rename", "startDate", "start")},
+			items:      []map[string]types.AttributeValue{both},
+		},
+		{
+			label:      "Duplicate IDs",
+			migrations: []Migration{changeKey, changeKey},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			table := newFakeTable()
+			for _, item := range test.items {
+				table.put(item)
+			}
+
+			_, err := Run(ctx, table, "table", test.migrations, Options{SkipTable: true})
+			if err == nil || (test.expected != nil && !errors.Is(err, test.expected)) {
+				t.Errorf("expected error %v, got %v", test.expected, err)
+			}
+			if table.writes != 0 {
+				t.Errorf("expected no items to be written, got %d", table.writes)
+			}
+		})
+	}
+}
+
+// fakeMediaStore parses the links of objects in bucket and has the objects
+// of its keys, by size
+type fakeMediaStore map[string]int64
+
+func (f fakeMediaStore) ParseMediaLink(mediaLink string) *models.MediaRef {
+	if key, ok := strings.CutPrefix(mediaLink, "https://bucket.s3.amazonaws.com/"); ok {
+		return &models.MediaRef{Storage: models.StorageS3, Key: key}
+	}
+	return &models.MediaRef{Storage: models.StorageURL, URL: mediaLink}
+}
+
+func (f fakeMediaStore) GetFileInfo(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	size, ok := f[key]
+	if !ok {
+		return nil, nil
+	}
+	return &s3.HeadObjectOutput{ContentType: aws.String("image/png"), ContentLength: aws.Int64(size)}, nil
+}
+
+func TestMediaRefs(t *testing.T) {
+	projectItem := func(sortValue string, mediaLink string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"personalWebsiteType": &types.AttributeValueMemberS{Value: "Projects"},
+			"sortValue":           &types.AttributeValueMemberS{Value: sortValue},
+			"name":                &types.AttributeValueMemberS{Value: sortValue},
+			"mediaLink":           &types.AttributeValueMemberS{Value: mediaLink},
+			"notAModelField":      &types.AttributeValueMemberS{Value: "kept"},
+		}
+	}
+
+	for _, test := range []struct {
+		label       string
+		mediaLink   string
+		expectedRef models.MediaRef
+	}{
+		{
+			label:       "S3 object",
+			mediaLink:   "https://bucket.s3.amazonaws.com/projects/website/cover.png",
+			expectedRef: models.MediaRef{Storage: models.StorageS3, Key: "projects/website/cover.png", ContentType: "image/png", Size: 42},
+		},
+		{
+			label:       "Missing S3 object",
+			mediaLink:   "https://bucket.s3.amazonaws.com/projects/website/missing.png",
+			expectedRef: models.MediaRef{Storage: models.StorageS3, Key: "projects/website/missing.png"},
+		},
+		{
+			label:       "External link",
+			mediaLink:   "https://www.youtube.com/watch?v=1",
+			expectedRef: models.MediaRef{Storage: models.StorageURL, URL: "https://www.youtube.com/watch?v=1"},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			ctx := context.Background()
+			table := newFakeTable()
+			table.put(projectItem("Website", test.mediaLink))
+			media := fakeMediaStore{"projects/website/cover.png": 42}
+
+			for _, expectedChanged := range []int{1, 0} {
+				report, err := Run(ctx, table, "table", []Migration{MediaRefs("001-media-refs", media)}, Options{SkipTable: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.Migrations[0].Changed != expectedChanged {
+					t.Errorf("expected %d changed projects, got %+v", expectedChanged, report.Migrations[0])
+				}
+				// forget the migration, so the second run applies it again to the
+				// migrated project
+				delete(table.items, ItemKey{PersonalWebsiteType: PartitionKey, SortValue: "001-media-refs"})
+			}
+
+			item := table.items[ItemKey{PersonalWebsiteType: "Projects", SortValue: "Website"}]
+			var project models.Project
+			if err := attributevalue.UnmarshalMap(item, &project); err != nil {
+				t.Fatal(err)
+			}
+			if project.MediaLink != nil || project.MediaRef == nil || *project.MediaRef != test.expectedRef {
+				t.Errorf("expected reference %+v and no mediaLink, got %+v %v", test.expectedRef, project.MediaRef, project.MediaLink)
+			}
+			if kept, ok := item["notAModelField"].(*types.AttributeValueMemberS); !ok || kept.Value != "kept" {
+				t.Errorf("expected attributes that are not media to be kept, got %v", item["notAModelField"])
+			}
+		})
+	}
+}
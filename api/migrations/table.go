@@ -0,0 +1,218 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// StartDateIndex is the global secondary index of the table on sortValue
+const StartDateIndex = "startDateIndex"
+
+const (
+	partitionKeyName = "personalWebsiteType"
+	sortKeyName      = "sortValue"
+	// capacity of the table and its indexes when created, the same as the
+	// deploy templates
+	capacityUnits = 5
+	// how many times the table is described while waiting for it to be active
+	maxWaitAttempts = 60
+)
+
+// ErrSchemaMismatch is returned for existing tables whose keys are not
+// personalWebsiteType and sortValue, which cannot be changed by a migration
+var ErrSchemaMismatch = errors.New("table schema does not match")
+
+// pollInterval is how long to wait between describing a table that is being
+// created or updated
+var pollInterval = 5 * time.Second
+
+// TableAPI is the part of the DynamoDB client migrations use. The DynamoDB
+// client implements it.
+type TableAPI interface {
+	dynamodb.DescribeTableAPIClient
+	dynamodb.QueryAPIClient
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+type TableReport struct {
+	// Created is set when the table did not exist
+	Created bool `json:"created"`
+	// CreatedIndexes are the global secondary indexes the table did not have
+	CreatedIndexes []string `json:"createdIndexes"`
+}
+
+// keySchema is the primary key of the table
+func keySchema() []types.KeySchemaElement {
+	return []types.KeySchemaElement{
+		{AttributeName: aws.String(partitionKeyName), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(sortKeyName), KeyType: types.KeyTypeRange},
+	}
+}
+
+func attributeDefinitions() []types.AttributeDefinition {
+	return []types.AttributeDefinition{
+		{AttributeName: aws.String(partitionKeyName), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(sortKeyName), AttributeType: types.ScalarAttributeTypeS},
+	}
+}
+
+// globalSecondaryIndexes are the indexes of the table, the same as
+// json/create-table.json
+func globalSecondaryIndexes() []types.GlobalSecondaryIndex {
+	return []types.GlobalSecondaryIndex{
+		{
+			IndexName: aws.String(StartDateIndex),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String(sortKeyName), KeyType: types.KeyTypeHash},
+			},
+			Projection:            &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			ProvisionedThroughput: provisionedThroughput(),
+		},
+	}
+}
+
+func provisionedThroughput() *types.ProvisionedThroughput {
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(capacityUnits),
+		WriteCapacityUnits: aws.Int64(capacityUnits),
+	}
+}
+
+// EnsureTable creates the table with its global secondary indexes if it does
+// not exist, and creates the indexes an existing table does not have, one at
+// a time as DynamoDB requires. It waits for the table and indexes to be
+// active. With dryRun it only reports what it would create.
+func EnsureTable(ctx context.Context, svc TableAPI, tableName string, dryRun bool) (TableReport, error) {
+	report := TableReport{CreatedIndexes: make([]string, 0)}
+
+	output, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		report.Created = true
+		for _, index := range globalSecondaryIndexes() {
+			report.CreatedIndexes = append(report.CreatedIndexes, aws.ToString(index.IndexName))
+		}
+		if dryRun {
+			return report, nil
+		}
+		_, err := svc.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName:              aws.String(tableName),
+			KeySchema:              keySchema(),
+			AttributeDefinitions:   attributeDefinitions(),
+			GlobalSecondaryIndexes: globalSecondaryIndexes(),
+			ProvisionedThroughput:  provisionedThroughput(),
+		})
+		if err != nil {
+			return report, fmt.Errorf("failed to create table %s: %w", tableName, err)
+		}
+		return report, waitForTable(ctx, svc, tableName)
+	}
+	if err != nil {
+		return report, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+
+	table := output.Table
+	if !sameKeySchema(table.KeySchema, keySchema()) {
+		return report, fmt.Errorf("%w: %s has keys %s, expected %s and %s", ErrSchemaMismatch, tableName, keyNames(table.KeySchema), partitionKeyName, sortKeyName)
+	}
+
+	existing := make(map[string]bool)
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = true
+	}
+	onDemand := table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode == types.BillingModePayPerRequest
+	for _, index := range globalSecondaryIndexes() {
+		name := aws.ToString(index.IndexName)
+		if existing[name] {
+			continue
+		}
+		report.CreatedIndexes = append(report.CreatedIndexes, name)
+		if dryRun {
+			continue
+		}
+
+		create := &types.CreateGlobalSecondaryIndexAction{
+			IndexName:             index.IndexName,
+			KeySchema:             index.KeySchema,
+			Projection:            index.Projection,
+			ProvisionedThroughput: index.ProvisionedThroughput,
+		}
+		// on demand tables reject the capacity of their indexes
+		if onDemand {
+			create.ProvisionedThroughput = nil
+		}
+		_, err := svc.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:                   aws.String(tableName),
+			AttributeDefinitions:        attributeDefinitions(),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{Create: create}},
+		})
+		if err != nil {
+			return report, fmt.Errorf("failed to create index %s of table %s: %w", name, tableName, err)
+		}
+		if err := waitForTable(ctx, svc, tableName); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// waitForTable describes the table until it and its indexes are active
+func waitForTable(ctx context.Context, svc TableAPI, tableName string) error {
+	for attempt := 0; attempt < maxWaitAttempts; attempt++ {
+		output, err := svc.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			return fmt.Errorf("failed to describe table %s: %w", tableName, err)
+		}
+		if tableActive(output.Table) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+	return fmt.Errorf("table %s is not active after %d attempts", tableName, maxWaitAttempts)
+}
+
+func tableActive(table *types.TableDescription) bool {
+	if table == nil || table.TableStatus != types.TableStatusActive {
+		return false
+	}
+	for _, index := range table.GlobalSecondaryIndexes {
+		if index.IndexStatus != types.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+func sameKeySchema(a []types.KeySchemaElement, b []types.KeySchemaElement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if aws.ToString(a[i].AttributeName) != aws.ToString(b[i].AttributeName) || a[i].KeyType != b[i].KeyType {
+			return false
+		}
+	}
+	return true
+}
+
+func keyNames(schema []types.KeySchemaElement) []string {
+	names := make([]string, len(schema))
+	for i, key := range schema {
+		names[i] = aws.ToString(key.AttributeName)
+	}
+	return names
+}
@@ -1,6 +1,7 @@
 package models
 
 type FileData struct {
+	FieldName   string
 	Filename    string
 	Content     []byte
 	ContentType string
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// JSONResumeSchema is the version of the JSON Resume schema JSONResume follows
+const JSONResumeSchema = "https://raw.githubusercontent.com/jsonresume/resume-schema/v1.0.0/schema.json"
+
+// JSONResume is the portfolio in the JSON Resume format, https://jsonresume.org/schema
+type JSONResume struct {
+	Schema   string              `json:"$schema"`
+	Work     []JSONResumeWork    `json:"work"`
+	Skills   []JSONResumeSkill   `json:"skills"`
+	Projects []JSONResumeProject `json:"projects"`
+}
+
+type JSONResumeWork struct {
+	Name       string   `json:"name"`
+	Position   string   `json:"position,omitempty"`
+	Location   string   `json:"location,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+type JSONResumeSkill struct {
+	Name     string   `json:"name"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type JSONResumeProject struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Type        string   `json:"type,omitempty"`
+}
+
+// JSONResume maps the portfolio to the JSON Resume format. Work maps to work,
+// every category of SkillsTools to a skill and Projects to projects.
+func (p *Portfolio) JSONResume() JSONResume {
+	resume := JSONResume{
+		Schema:   JSONResumeSchema,
+		Work:     make([]JSONResumeWork, 0, len(p.Work)),
+		Skills:   []JSONResumeSkill{},
+		Projects: make([]JSONResumeProject, 0, len(p.Projects)),
+	}
+
+	for _, work := range p.Work {
+		resume.Work = append(resume.Work, JSONResumeWork{
+			Name:       work.Company,
+			Position:   work.JobTitle,
+			Location:   joinNonEmpty(", ", work.Location.City, work.Location.State),
+			StartDate:  resumeDate(work.StartDate),
+			EndDate:    resumeDate(work.EndDate),
+			Summary:    work.JobRole,
+			Highlights: work.JobDescription,
+		})
+	}
+
+	for _, skillsTools := range p.SkillsTools {
+		for _, category := range skillsTools.Categories {
+			resume.Skills = append(resume.Skills, JSONResumeSkill{
+				Name:     category.Category,
+				Keywords: category.List,
+			})
+		}
+	}
+
+	for _, project := range p.Projects {
+		resumeProject := JSONResumeProject{
+			Name:        project.Name,
+			Description: project.Description,
+			Highlights:  project.Tasks,
+			Keywords:    project.Tools,
+			StartDate:   resumeDate(project.StartDate),
+			EndDate:     resumeDate(project.EndDate),
+			Type:        project.Category,
+		}
+		if project.CloudServices != nil {
+			resumeProject.Keywords = append(append([]string{}, project.Tools...), *project.CloudServices...)
+		}
+		if project.Link != nil {
+			resumeProject.URL = *project.Link
+		}
+		if project.Role != "" {
+			resumeProject.Roles = []string{project.Role}
+		}
+		resume.Projects = append(resume.Projects, resumeProject)
+	}
+
+	return resume
+}
+
+// layouts of the start and end dates of work and projects, e.g. 2019-06-11 or
+// Jan 2024, with the ISO 8601 layout of their precision and its length
+var dateLayouts = []struct {
+	layout string
+	iso    string
+	years  int
+	months int
+	days   int
+}{
+	{"2006-01-02", "2006-01-02", 0, 0, 1},
+	{"2006-01", "2006-01", 0, 1, 0},
+	{"Jan 2006", "2006-01", 0, 1, 0},
+	{"January 2006", "2006-01", 0, 1, 0},
+	{"01/2006", "2006-01", 0, 1, 0},
+	{"2006", "2006", 1, 0, 0},
+}
+
+// ParseDate returns the period a start or end date of work or a project
+// covers, from start up to but not including end, e.g. all of January 2024
+// for Jan 2024. ok is false for dates that are not known layouts, such as
+// Present.
+func ParseDate(date string) (start time.Time, end time.Time, ok bool) {
+	start, layout, ok := parseDate(date)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.AddDate(dateLayouts[layout].years, dateLayouts[layout].months, dateLayouts[layout].days), true
+}
+
+func parseDate(date string) (time.Time, int, bool) {
+	date = strings.TrimSpace(date)
+	for i, layout := range dateLayouts {
+		if t, err := time.Parse(layout.layout, date); err == nil {
+			return t, i, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// resumeDate returns date in the ISO 8601 format of JSON Resume, with the
+// precision it was written in. Dates that are not known layouts, such as
+// Present, return "".
+func resumeDate(date string) string {
+	if t, layout, ok := parseDate(date); ok {
+		return t.Format(dateLayouts[layout].iso)
+	}
+	return ""
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+)
+
+// MediaItem is an image or video in a project's media gallery. Items are
+// displayed in the order they are stored. MediaLink is resolved from Ref in
+// responses.
+type MediaItem struct {
+	ID          string         `json:"id" dynamodbav:"id"`
+	MediaLink   string         `json:"mediaLink" dynamodbav:"mediaLink"`
+	Ref         *MediaRef      `json:"-" dynamodbav:"ref,omitempty"`
+	ContentType string         `json:"contentType" dynamodbav:"contentType"`
+	Caption     *string        `json:"caption" dynamodbav:"caption"`
+	AltText     *string        `json:"altText" dynamodbav:"altText"`
+	Variants    []MediaVariant `json:"variants" dynamodbav:"variants"`
+	Poster      *MediaVariant  `json:"poster" dynamodbav:"poster"`
+	PosterTime  *float64       `json:"posterTime" dynamodbav:"posterTime"`
+}
+
+// MediaVariant is a resized copy of an uploaded image. Every width is
+// generated in the format of the original and as WebP. Posters of videos and
+// documents are stored as a variant named poster.
+type MediaVariant struct {
+	Name        string    `json:"name" dynamodbav:"name"`
+	Width       int       `json:"width" dynamodbav:"width"`
+	Height      int       `json:"height" dynamodbav:"height"`
+	ContentType string    `json:"contentType" dynamodbav:"contentType"`
+	MediaLink   string    `json:"mediaLink" dynamodbav:"mediaLink"`
+	Ref         *MediaRef `json:"-" dynamodbav:"ref,omitempty"`
+}
+
+// ProjectMedia is the request body of the project media endpoints. Captions,
+// AltTexts, poster files and PosterTimes apply to uploaded files in order,
+// Order lists every gallery item ID in the new order and ID is the item to
+// remove.
+type ProjectMedia struct {
+	PersonalWebsiteType string    `json:"personalWebsiteType"`
+	SortValue           string    `json:"sortValue"`
+	Captions            []string  `json:"captions"`
+	AltTexts            []string  `json:"altTexts"`
+	PosterTimes         []float64 `json:"posterTimes"`
+	Order               []string  `json:"order"`
+	ID                  string    `json:"id"`
+}
+
+// GetRef returns where the item is stored
+func (m *MediaItem) GetRef() *MediaRef {
+	return refOrLink(m.Ref, m.MediaLink)
+}
+
+func (m *MediaItem) IsS3Bucket() bool {
+	return m.GetRef().IsS3()
+}
+
+// GetFileName returns the S3 object key of the item
+func (m *MediaItem) GetFileName() (string, error) {
+	if !m.IsS3Bucket() {
+		return "", fmt.Errorf("media item %s is not an S3 object", m.ID)
+	}
+	return m.GetRef().Key, nil
+}
+
+// GetRef returns where the variant is stored
+func (v *MediaVariant) GetRef() *MediaRef {
+	return refOrLink(v.Ref, v.MediaLink)
+}
+
+// GetFileName returns the S3 object key of the variant
+func (v *MediaVariant) GetFileName() (string, error) {
+	ref := v.GetRef()
+	if !ref.IsS3() {
+		return "", fmt.Errorf("%s variant is not an S3 object", v.Name)
+	}
+	return ref.Key, nil
+}
+
+// setRef replaces the mediaLink of an item stored before references with
+// the reference parse returns, and reports whether the item changed
+func (m *MediaItem) setRef(parse func(mediaLink string) *MediaRef) bool {
+	changed := false
+	if m.MediaLink != "" {
+		if m.Ref == nil {
+			m.Ref = parse(m.MediaLink)
+		}
+		m.MediaLink = ""
+		changed = true
+	}
+	return setVariantRefs(m.Variants, m.Poster, parse) || changed
+}
+
+// setVariantRefs is setRef for variants and the poster
+func setVariantRefs(variants []MediaVariant, poster *MediaVariant, parse func(mediaLink string) *MediaRef) bool {
+	changed := false
+	for i := range variants {
+		changed = variants[i].setRef(parse) || changed
+	}
+	if poster != nil {
+		changed = poster.setRef(parse) || changed
+	}
+	return changed
+}
+
+func (v *MediaVariant) setRef(parse func(mediaLink string) *MediaRef) bool {
+	if v.MediaLink == "" {
+		return false
+	}
+	if v.Ref == nil {
+		v.Ref = parse(v.MediaLink)
+	}
+	v.MediaLink = ""
+	return true
+}
+
+// variantRefs returns the references of variants and the poster
+func variantRefs(variants []MediaVariant, poster *MediaVariant) []*MediaRef {
+	var refs []*MediaRef
+	for i := range variants {
+		if ref := variants[i].GetRef(); ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+	if poster != nil {
+		if ref := poster.GetRef(); ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
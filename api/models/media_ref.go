@@ -0,0 +1,95 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+// storage kinds of a MediaRef
+const (
+	// StorageS3 is an object in the media bucket
+	StorageS3 = "s3"
+	// StorageURL is an external link, e.g. a video hosted elsewhere
+	StorageURL = "url"
+)
+
+// MediaRef is where media is stored. Projects persist references instead of
+// links, and responses resolve them to a mediaLink the object can be fetched
+// from, so links do not go stale when the bucket, region or URL mode changes.
+type MediaRef struct {
+	Storage     string `json:"storage" dynamodbav:"storage"`
+	Bucket      string `json:"bucket,omitempty" dynamodbav:"bucket,omitempty"`
+	Key         string `json:"key,omitempty" dynamodbav:"key,omitempty"`
+	URL         string `json:"url,omitempty" dynamodbav:"url,omitempty"`
+	ContentType string `json:"contentType,omitempty" dynamodbav:"contentType,omitempty"`
+	Size        int64  `json:"size,omitempty" dynamodbav:"size,omitempty"`
+}
+
+// IsS3 reports whether the reference is an object in the media bucket
+func (r *MediaRef) IsS3() bool {
+	return r != nil && r.Storage == StorageS3 && r.Key != ""
+}
+
+// ParseMediaLink returns the reference of a link, or nil if it is empty. S3
+// URLs are recognized in the virtual hosted and path styles of any region,
+// e.g. https://my-bucket.s3.us-east-2.amazonaws.com/key and
+// https://s3.amazonaws.com/my-bucket/key, any other link is external.
+func ParseMediaLink(mediaLink string) *MediaRef {
+	if mediaLink == "" {
+		return nil
+	}
+	parsedURL, err := url.Parse(mediaLink)
+	if err != nil {
+		return &MediaRef{Storage: StorageURL, URL: mediaLink}
+	}
+	bucketName, key, ok := parseS3URL(parsedURL)
+	if !ok {
+		return &MediaRef{Storage: StorageURL, URL: mediaLink}
+	}
+	return &MediaRef{Storage: StorageS3, Bucket: bucketName, Key: key}
+}
+
+// parseS3URL returns the bucket and key of an S3 URL. The host is
+// [bucket.]s3[.dualstack][.region].amazonaws.com, with the legacy s3-region
+// form as well, and path style URLs have the bucket as the first segment.
+func parseS3URL(parsedURL *url.URL) (bucketName string, key string, ok bool) {
+	host := strings.ToLower(parsedURL.Hostname())
+	rest, found := strings.CutSuffix(host, ".amazonaws.com")
+	if !found {
+		if rest, found = strings.CutSuffix(host, ".amazonaws.com.cn"); !found {
+			return "", "", false
+		}
+	}
+
+	// bucket names can contain dots and the s3 label, so use the last one
+	labels := strings.Split(rest, ".")
+	s3Label := -1
+	for i := len(labels) - 1; i >= 0; i-- {
+		if labels[i] == "s3" || strings.HasPrefix(labels[i], "s3-") {
+			s3Label = i
+			break
+		}
+	}
+	if s3Label == -1 {
+		return "", "", false
+	}
+
+	key = strings.TrimPrefix(parsedURL.Path, "/")
+	bucketName = strings.Join(labels[:s3Label], ".")
+	if bucketName == "" {
+		bucketName, key, _ = strings.Cut(key, "/")
+	}
+	if bucketName == "" || key == "" {
+		return "", "", false
+	}
+	return bucketName, key, true
+}
+
+// refOrLink returns ref, or for media stored before references the reference
+// parsed from its mediaLink
+func refOrLink(ref *MediaRef, mediaLink string) *MediaRef {
+	if ref != nil {
+		return ref
+	}
+	return ParseMediaLink(mediaLink)
+}
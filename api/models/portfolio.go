@@ -0,0 +1,15 @@
+package models
+
+// Portfolio combines all content of the personal website in one document
+type Portfolio struct {
+	Work        []Work           `json:"work"`
+	SkillsTools []SkillsTools    `json:"skillsTools"`
+	Projects    []Project        `json:"projects"`
+	Errors      []PortfolioError `json:"errors,omitempty"`
+}
+
+// PortfolioError reports a section of the portfolio that could not be loaded
+type PortfolioError struct {
+	Section string `json:"section"`
+	Message string `json:"message"`
+}
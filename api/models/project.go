@@ -2,55 +2,115 @@ package models
 
 import (
 	"fmt"
-	"net/url"
-	"path"
-	"strings"
 )
 
 type Project struct {
-	PersonalWebsiteType string    `json:"personalWebsiteType" dynamodbav:"personalWebsiteType"`
-	SortValue           string    `json:"sortValue" dynamodbav:"sortValue"`
-	Category            string    `json:"category" dynamodbav:"category"`
-	Name                string    `json:"name" dynamodbav:"name"`
-	Description         string    `json:"description" dynamodbav:"description"`
-	FeaturesDescription string    `json:"featuresDescription" dynamodbav:"featuresDescription"`
-	Role                string    `json:"role" dynamodbav:"role"`
-	Tasks               []string  `json:"tasks" dynamodbav:"tasks"`
-	TeamSize            *string   `json:"teamSize" dynamodbav:"teamSize"`
-	TeamRoles           *[]string `json:"teamRoles" dynamodbav:"teamRoles"`
-	CloudServices       *[]string `json:"cloudServices" dynamodbav:"cloudServices"`
-	Tools               []string  `json:"tools" dynamodbav:"tools"`
-	Duration            string    `json:"duration" dynamodbav:"duration"`
-	StartDate           string    `json:"startDate" dynamodbav:"startDate"`
-	EndDate             string    `json:"endDate" dynamodbav:"endDate"`
-	Notes               *string   `json:"notes" dynamodbav:"notes"`
-	Link                *string   `json:"link" dynamodbav:"link"`
-	LinkType            *string   `json:"linkType" dynamodbav:"linkType"`
-	MediaLink           *string   `json:"mediaLink" dynamodbav:"mediaLink"`
+	PersonalWebsiteType string         `json:"personalWebsiteType" dynamodbav:"personalWebsiteType"`
+	SortValue           string         `json:"sortValue" dynamodbav:"sortValue"`
+	Category            string         `json:"category" dynamodbav:"category"`
+	Name                string         `json:"name" dynamodbav:"name"`
+	Description         string         `json:"description" dynamodbav:"description"`
+	FeaturesDescription string         `json:"featuresDescription" dynamodbav:"featuresDescription"`
+	Role                string         `json:"role" dynamodbav:"role"`
+	Tasks               []string       `json:"tasks" dynamodbav:"tasks"`
+	TeamSize            *string        `json:"teamSize" dynamodbav:"teamSize"`
+	TeamRoles           *[]string      `json:"teamRoles" dynamodbav:"teamRoles"`
+	CloudServices       *[]string      `json:"cloudServices" dynamodbav:"cloudServices"`
+	Tools               []string       `json:"tools" dynamodbav:"tools"`
+	Duration            string         `json:"duration" dynamodbav:"duration"`
+	StartDate           string         `json:"startDate" dynamodbav:"startDate"`
+	EndDate             string         `json:"endDate" dynamodbav:"endDate"`
+	Notes               *string        `json:"notes" dynamodbav:"notes"`
+	Link                *string        `json:"link" dynamodbav:"link"`
+	LinkType            *string        `json:"linkType" dynamodbav:"linkType"`
+	MediaLink           *string        `json:"mediaLink" dynamodbav:"mediaLink"`
+	MediaRef            *MediaRef      `json:"-" dynamodbav:"mediaRef"`
+	MediaVariants       []MediaVariant `json:"mediaVariants" dynamodbav:"mediaVariants"`
+	MediaPoster         *MediaVariant  `json:"mediaPoster" dynamodbav:"mediaPoster"`
+	MediaPosterTime     *float64       `json:"mediaPosterTime" dynamodbav:"mediaPosterTime"`
+	Media               []MediaItem    `json:"media" dynamodbav:"media"`
 }
 
-func (p *Project) MediaLinkIsS3Bucket() bool {
-	if p.MediaLink == nil {
-		return false
+// GetMediaRef returns where the mediaLink is stored
+func (p *Project) GetMediaRef() *MediaRef {
+	if p.MediaRef != nil || p.MediaLink == nil {
+		return p.MediaRef
 	}
-	return strings.Contains(*p.MediaLink, "s3.amazonaws.com")
+	return ParseMediaLink(*p.MediaLink)
+}
+
+func (p *Project) MediaLinkIsS3Bucket() bool {
+	return p.GetMediaRef().IsS3()
 }
 
+// GetFileNameFromMediaLink returns the S3 object key of the mediaLink. Keys are
+// either a filename at the root of the bucket or a content addressed key under
+// the project's prefix, e.g. projects/personal-website-bff86b0df1a97b87/<sha256>.png
 func (p *Project) GetFileNameFromMediaLink() (string, error) {
-	if p.MediaLink == nil {
+	ref := p.GetMediaRef()
+	if ref == nil {
 		return "", fmt.Errorf("no mediaLink found in Project")
 	}
-	parsedURL, err := url.Parse(*p.MediaLink)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse url: %w", err)
+	if !ref.IsS3() {
+		return "", fmt.Errorf("mediaLink of project %s is not an S3 object", p.SortValue)
+	}
+	return ref.Key, nil
+}
+
+// MediaRefs returns the references of the mediaLink, every item in the media
+// gallery and their variants and posters
+func (p *Project) MediaRefs() []*MediaRef {
+	var refs []*MediaRef
+	if ref := p.GetMediaRef(); ref != nil {
+		refs = append(refs, ref)
+	}
+	refs = append(refs, variantRefs(p.MediaVariants, p.MediaPoster)...)
+	for i := range p.Media {
+		if ref := p.Media[i].GetRef(); ref != nil {
+			refs = append(refs, ref)
+		}
+		refs = append(refs, variantRefs(p.Media[i].Variants, p.Media[i].Poster)...)
 	}
+	return refs
+}
 
-	filename := path.Base(parsedURL.Path)
+// MediaFileNames returns the S3 object keys of the mediaLink, every item in
+// the media gallery and their variants and posters
+func (p *Project) MediaFileNames() []string {
+	var fileNames []string
+	for _, ref := range p.MediaRefs() {
+		if ref.IsS3() {
+			fileNames = append(fileNames, ref.Key)
+		}
+	}
+	return fileNames
+}
 
-	// Check if we actually got a filename (not empty or just "/")
-	if filename == "." || filename == "/" || filename == "" {
-		return "", fmt.Errorf("no filename found in MediaLink")
+// SetMediaRefs replaces the mediaLinks of media stored before references
+// with the references parse returns, and reports whether any were replaced.
+// Links are cleared so only references are stored.
+func (p *Project) SetMediaRefs(parse func(mediaLink string) *MediaRef) bool {
+	changed := false
+	if p.MediaLink != nil {
+		if p.MediaRef == nil {
+			p.MediaRef = parse(*p.MediaLink)
+		}
+		p.MediaLink = nil
+		changed = true
 	}
+	changed = setVariantRefs(p.MediaVariants, p.MediaPoster, parse) || changed
+	for i := range p.Media {
+		changed = p.Media[i].setRef(parse) || changed
+	}
+	return changed
+}
 
-	return filename, nil
+// MediaIndex returns the index of the gallery item with id, or -1
+func (p *Project) MediaIndex(id string) int {
+	for i, item := range p.Media {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
 }
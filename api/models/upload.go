@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// UploadRequest asks for a presigned URL to upload a project's media directly
+// to S3. ChecksumSHA256 is the hex encoded SHA-256 of the file content.
+type UploadRequest struct {
+	PersonalWebsiteType string `json:"personalWebsiteType"`
+	SortValue           string `json:"sortValue"`
+	Filename            string `json:"filename"`
+	ContentType         string `json:"contentType"`
+	ContentLength       int64  `json:"contentLength"`
+	ChecksumSHA256      string `json:"checksumSha256"`
+}
+
+// UploadURL is a presigned request for uploading a file to Key. Headers must
+// be sent with the request as is. Exists is set when the content was already
+// uploaded, in which case the upload can be skipped.
+type UploadURL struct {
+	Key       string            `json:"key"`
+	UploadURL string            `json:"uploadUrl"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	Exists    bool              `json:"exists"`
+}
+
+// UploadConfirmation sets the project's mediaLink to an object uploaded with
+// an UploadURL
+type UploadConfirmation struct {
+	PersonalWebsiteType string `json:"personalWebsiteType"`
+	SortValue           string `json:"sortValue"`
+	Key                 string `json:"key"`
+}
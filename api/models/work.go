@@ -1,15 +1,26 @@
 package models
 
 type Work struct {
-	PersonalWebsiteType string   `json:"personalWebsiteType" dynamodbav:"personalWebsiteType"`
-	SortValue           string   `json:"sortValue" dynamodbav:"sortValue"`
-	JobTitle            string   `json:"jobTitle" dynamodbav:"jobTitle"`
-	Company             string   `json:"company" dynamodbav:"company"`
-	Location            Location `json:"location" dynamodbav:"location"`
-	StartDate           string   `json:"startDate" dynamodbav:"startDate"`
-	EndDate             string   `json:"endDate" dynamodbav:"endDate"`
-	JobRole             string   `json:"jobRole" dynamodbav:"jobRole"`
-	JobDescription      []string `json:"jobDescription" dynamodbav:"jobDescription"`
+	PersonalWebsiteType string    `json:"personalWebsiteType" dynamodbav:"personalWebsiteType"`
+	SortValue           string    `json:"sortValue" dynamodbav:"sortValue"`
+	JobTitle            string    `json:"jobTitle" dynamodbav:"jobTitle"`
+	Company             string    `json:"company" dynamodbav:"company"`
+	CompanyLogo         *string   `json:"companyLogo" dynamodbav:"companyLogo"`
+	CompanyLogoRef      *MediaRef `json:"-" dynamodbav:"companyLogoRef"`
+	Location            Location  `json:"location" dynamodbav:"location"`
+	StartDate           string    `json:"startDate" dynamodbav:"startDate"`
+	EndDate             string    `json:"endDate" dynamodbav:"endDate"`
+	JobRole             string    `json:"jobRole" dynamodbav:"jobRole"`
+	JobDescription      []string  `json:"jobDescription" dynamodbav:"jobDescription"`
+}
+
+// GetCompanyLogoRef returns where the company logo is stored. CompanyLogo is
+// resolved from it in responses.
+func (w *Work) GetCompanyLogoRef() *MediaRef {
+	if w.CompanyLogoRef != nil || w.CompanyLogo == nil {
+		return w.CompanyLogoRef
+	}
+	return ParseMediaLink(*w.CompanyLogo)
 }
 
 type Location struct {
@@ -0,0 +1,140 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+)
+
+// DefaultMinAge keeps objects uploaded by requests that are still in flight
+// from being reported, since uploads happen before the project is written
+const DefaultMinAge = time.Hour
+
+type Options struct {
+	// Delete removes orphaned objects instead of only reporting them
+	Delete bool
+	// MinAge is how old an object must be before it can be an orphan
+	MinAge time.Duration
+}
+
+// MediaStore is the bucket media is reconciled with. *bucket.Bucket
+// implements it.
+type MediaStore interface {
+	ListFilesInS3(ctx context.Context, prefix string) ([]types.Object, error)
+	DeleteFileFromS3(ctx context.Context, key string) error
+}
+
+type Report struct {
+	Objects      int            `json:"objects"`
+	Projects     int            `json:"projects"`
+	Orphans      []string       `json:"orphans"`
+	Deleted      []string       `json:"deleted"`
+	MissingMedia []MissingMedia `json:"missingMedia"`
+}
+
+// MissingMedia is a project or work whose media points to an object that does not exist
+type MissingMedia struct {
+	SortValue string `json:"sortValue"`
+	Key       string `json:"key"`
+}
+
+// mediaKeys are the S3 keys of the media of a project or work
+type mediaKeys struct {
+	sortValue string
+	keys      []string
+}
+
+// Run compares the objects under bucket.MediaPrefixes with the mediaLink and
+// media gallery of every project and the company logo of every work. Objects
+// nothing references are reported as orphans, and deleted when opts.Delete is
+// set. Objects outside the media prefixes are never listed, so they cannot be
+// deleted. Items referencing objects that do not exist are reported as
+// missing media.
+func Run(ctx context.Context, db dynamodb.QueryAPIClient, b MediaStore, tableName string, opts Options) (Report, error) {
+	report := Report{
+		Orphans:      make([]string, 0),
+		Deleted:      make([]string, 0),
+		MissingMedia: make([]MissingMedia, 0),
+	}
+
+	var objects []types.Object
+	for _, prefix := range bucket.MediaPrefixes {
+		prefixObjects, err := b.ListFilesInS3(ctx, prefix)
+		if err != nil {
+			return report, err
+		}
+		objects = append(objects, prefixObjects...)
+	}
+	report.Objects = len(objects)
+
+	projects, err := database.GetProjects(ctx, db, tableName)
+	if err != nil {
+		return report, fmt.Errorf("failed to get projects: %w", err)
+	}
+	report.Projects = len(projects)
+
+	work, err := database.GetWork(ctx, db, tableName)
+	if err != nil {
+		return report, fmt.Errorf("failed to get work: %w", err)
+	}
+
+	var items []mediaKeys
+	for _, project := range projects {
+		items = append(items, mediaKeys{project.SortValue, project.MediaFileNames()})
+	}
+	for _, w := range work {
+		if ref := w.GetCompanyLogoRef(); ref.IsS3() {
+			items = append(items, mediaKeys{w.SortValue, []string{ref.Key}})
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, item := range items {
+		for _, key := range item.keys {
+			referenced[key] = true
+		}
+	}
+
+	existing := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		key := aws.ToString(object.Key)
+		existing[key] = true
+
+		if referenced[key] {
+			continue
+		}
+		if object.LastModified != nil && time.Since(*object.LastModified) < opts.MinAge {
+			log.Printf("skipping recently uploaded object %s", key)
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, key)
+		if opts.Delete {
+			if err := b.DeleteFileFromS3(ctx, key); err != nil {
+				log.Printf("error in deleting orphaned object %s: %v", key, err)
+				continue
+			}
+			report.Deleted = append(report.Deleted, key)
+		}
+	}
+
+	for _, item := range items {
+		for _, key := range item.keys {
+			if !existing[key] {
+				report.MissingMedia = append(report.MissingMedia, MissingMedia{
+					SortValue: item.sortValue,
+					Key:       key,
+				})
+			}
+		}
+	}
+
+	return report, nil
+}
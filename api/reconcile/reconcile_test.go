@@ -0,0 +1,151 @@
+package reconcile
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// fakeTable returns items by personalWebsiteType
+type fakeTable map[string][]any
+
+func (f fakeTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	partitionKey := params.ExpressionAttributeValues[":partitionKey"].(*dynamodbtypes.AttributeValueMemberS).Value
+	output := &dynamodb.QueryOutput{}
+	for _, item := range f[partitionKey] {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return nil, err
+		}
+		output.Items = append(output.Items, av)
+	}
+	return output, nil
+}
+
+// fakeMediaStore stores when each object was last modified by key
+type fakeMediaStore struct {
+	objects  map[string]time.Time
+	prefixes []string
+}
+
+func (f *fakeMediaStore) ListFilesInS3(ctx context.Context, prefix string) ([]types.Object, error) {
+	f.prefixes = append(f.prefixes, prefix)
+	var objects []types.Object
+	for key, modified := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, types.Object{Key: aws.String(key), LastModified: aws.Time(modified)})
+		}
+	}
+	return objects, nil
+}
+
+func (f *fakeMediaStore) DeleteFileFromS3(ctx context.Context, key string) error {
+	delete(f.objects, key)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	old := time.Now().Add(-24 * time.Hour)
+	logo := "https://bucket.s3.amazonaws.com/work/new-company/logo.png"
+	table := fakeTable{
+		"Projects": {models.Project{
+			PersonalWebsiteType: "Projects",
+			SortValue:           "Personal Website",
+			MediaRef:            &models.MediaRef{Storage: models.StorageS3, Key: "projects/personal-website/cover.png"},
+			Media: []models.MediaItem{
+				{Ref: &models.MediaRef{Storage: models.StorageS3, Key: "projects/personal-website/missing.png"}},
+			},
+		}},
+		"Work": {models.Work{
+			PersonalWebsiteType: "Work",
+			SortValue:           "2019-06-11",
+			CompanyLogo:         &logo,
+		}},
+	}
+
+	for _, test := range []struct {
+		label           string
+		opts            Options
+		expectedOrphans []string
+		expectedDeleted []string
+		expectedKept    []string
+	}{
+		{
+			label:           "Report",
+			opts:            Options{MinAge: DefaultMinAge},
+			expectedOrphans: []string{"projects/old-project/cover.png", "work/old-company/logo.png"},
+			expectedDeleted: []string{},
+			expectedKept: []string{
+				"projects/old-project/cover.png",
+				"work/old-company/logo.png",
+			},
+		},
+		{
+			label:           "Delete",
+			opts:            Options{Delete: true, MinAge: DefaultMinAge},
+			expectedOrphans: []string{"projects/old-project/cover.png", "work/old-company/logo.png"},
+			expectedDeleted: []string{"projects/old-project/cover.png", "work/old-company/logo.png"},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			media := &fakeMediaStore{objects: map[string]time.Time{
+				"projects/personal-website/cover.png": old,
+				"projects/old-project/cover.png":      old,
+				"projects/new-project/upload.png":     time.Now(),
+				"work/new-company/logo.png":           old,
+				"work/old-company/logo.png":           old,
+				"templates/resume/default.html.tmpl":  old,
+				"exports/portfolio.zip":               old,
+			}}
+
+			report, err := Run(context.Background(), table, media, "table", test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sort.Strings(report.Orphans)
+			sort.Strings(report.Deleted)
+			if !reflect.DeepEqual(report.Orphans, test.expectedOrphans) {
+				t.Errorf("expected orphans %v, got %v", test.expectedOrphans, report.Orphans)
+			}
+			if !reflect.DeepEqual(report.Deleted, test.expectedDeleted) {
+				t.Errorf("expected deleted %v, got %v", test.expectedDeleted, report.Deleted)
+			}
+			expectedMissing := []MissingMedia{{SortValue: "Personal Website", Key: "projects/personal-website/missing.png"}}
+			if !reflect.DeepEqual(report.MissingMedia, expectedMissing) {
+				t.Errorf("expected missing media %v, got %v", expectedMissing, report.MissingMedia)
+			}
+			if report.Objects != 5 {
+				t.Errorf("expected the 5 media objects to be listed, got %d", report.Objects)
+			}
+
+			kept := append([]string{
+				"projects/personal-website/cover.png",
+				"projects/new-project/upload.png",
+				"work/new-company/logo.png",
+				"templates/resume/default.html.tmpl",
+				"exports/portfolio.zip",
+			}, test.expectedKept...)
+			for _, key := range kept {
+				if _, ok := media.objects[key]; !ok {
+					t.Errorf("expected %s to be kept", key)
+				}
+			}
+			for _, prefix := range media.prefixes {
+				if prefix != "projects/" && prefix != "work/" {
+					t.Errorf("expected only media prefixes to be listed, got %q", prefix)
+				}
+			}
+		})
+	}
+}
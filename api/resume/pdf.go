@@ -0,0 +1,124 @@
+package resume
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// date set as the creation and modification date of every PDF. With the
+// catalog sorted, the same resume renders the same bytes and keeps its ETag
+// between requests.
+var pdfDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	pdfMargin     = 18.0
+	pdfLineHeight = 5.0
+)
+
+// renderPDF lays out the resume on Letter pages in the layout of the default
+// templates
+func renderPDF(w io.Writer, resume *Resume) error {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetCreationDate(pdfDate)
+	pdf.SetModificationDate(pdfDate)
+	pdf.SetCatalogSort(true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	// the core fonts are encoded in cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr(resume.Title), false)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 10, tr(resume.Title), "", "L", false)
+
+	heading := func(text string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, tr(text), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+	}
+	subheading := func(text string) {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.MultiCell(0, 6, tr(text), "", "L", false)
+	}
+	meta := func(values ...string) {
+		var nonEmpty []string
+		for _, value := range values {
+			if value != "" {
+				nonEmpty = append(nonEmpty, value)
+			}
+		}
+		if len(nonEmpty) == 0 {
+			return
+		}
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.SetTextColor(85, 85, 85)
+		pdf.MultiCell(0, pdfLineHeight, tr(strings.Join(nonEmpty, " | ")), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+	}
+	paragraph := func(text string) {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, pdfLineHeight, tr(text), "", "L", false)
+	}
+	bullets := func(items []string) {
+		pdf.SetFont("Helvetica", "", 10)
+		for _, item := range items {
+			pdf.SetX(pdfMargin + 2)
+			pdf.CellFormat(4, pdfLineHeight, tr("•"), "", 0, "L", false, 0, "")
+			pdf.MultiCell(0, pdfLineHeight, tr(item), "", "L", false)
+		}
+	}
+	labelled := func(label string, text string) {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(pdf.GetStringWidth(tr(label))+1, pdfLineHeight, tr(label), "", 0, "L", false, 0, "")
+		paragraph(text)
+	}
+
+	if resume.Has(SectionWork) {
+		heading("Work Experience")
+		for _, work := range resume.Work {
+			subheading(work.JobTitle + ", " + work.Company)
+			meta(period(work.StartDate, work.EndDate), location(work.Location))
+			bullets(work.JobDescription)
+		}
+	}
+
+	if resume.Has(SectionSkills) {
+		heading("Skills and Tools")
+		for _, skillsTools := range resume.SkillsTools {
+			for _, category := range skillsTools.Categories {
+				labelled(category.Category+":", strings.Join(category.List, ", "))
+			}
+		}
+	}
+
+	if resume.Has(SectionProjects) {
+		heading("Projects")
+		for _, project := range resume.Projects {
+			title := project.Name
+			if project.Role != "" {
+				title += ", " + project.Role
+			}
+			subheading(title)
+			link := ""
+			if project.Link != nil {
+				link = *project.Link
+			}
+			meta(period(project.StartDate, project.EndDate), link)
+			if project.Description != "" {
+				paragraph(project.Description)
+			}
+			bullets(project.Tasks)
+			if len(project.Tools) > 0 {
+				labelled("Tools:", strings.Join(project.Tools, ", "))
+			}
+		}
+	}
+
+	return pdf.Output(w)
+}
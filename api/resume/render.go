@@ -0,0 +1,205 @@
+package resume
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+type Format string
+
+const (
+	Markdown Format = "md"
+	HTML     Format = "html"
+	PDF      Format = "pdf"
+)
+
+var Formats = []Format{HTML, Markdown, PDF}
+
+// ContentType returns the media type a resume in the format is served as
+func (f Format) ContentType() string {
+	switch f {
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	case HTML:
+		return "text/html; charset=utf-8"
+	case PDF:
+		return "application/pdf"
+	}
+	return ""
+}
+
+// DefaultTemplate is the name of the template used when none is requested.
+// It is embedded, and can be replaced by uploading a template of the same name.
+const DefaultTemplate = "default"
+
+var (
+	ErrTemplateNotFound = errors.New("resume template not found")
+
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// TemplateStore loads templates uploaded under bucket.ResumeTemplatePrefix.
+// GetFile returns nil for templates that do not exist. *bucket.Bucket
+// implements it.
+type TemplateStore interface {
+	GetFile(ctx context.Context, key string) ([]byte, error)
+}
+
+// Renderer renders resumes with templates from Store, falling back to the
+// embedded templates. Store can be nil to only use the embedded templates.
+type Renderer struct {
+	Store TemplateStore
+}
+
+// ValidTemplateName reports whether name can be the name of a template, lower
+// case letters, digits, dashes and underscores
+func ValidTemplateName(name string) bool {
+	return templateNamePattern.MatchString(name)
+}
+
+// TemplateKey returns the bucket key of the template name for format, e.g.
+// templates/resume/default.html.tmpl
+func TemplateKey(name string, format Format) string {
+	return bucket.ResumeTemplatePrefix + name + "." + string(format) + ".tmpl"
+}
+
+// Render writes resume to w in format. Markdown and HTML are rendered with the
+// template name, PDF is laid out from the resume itself.
+func (r *Renderer) Render(ctx context.Context, w io.Writer, format Format, name string, resume *Resume) error {
+	source, err := r.TemplateSource(ctx, format, name)
+	if err != nil {
+		return err
+	}
+	return RenderSource(w, format, name, source, resume)
+}
+
+// TemplateSource returns the source of the template name for format, the
+// uploaded template or the embedded one if there is no upload. PDFs have no
+// template.
+func (r *Renderer) TemplateSource(ctx context.Context, format Format, name string) (string, error) {
+	if format == PDF {
+		return "", nil
+	}
+	if format != Markdown && format != HTML {
+		return "", fmt.Errorf("unknown resume format %q", format)
+	}
+	if name == "" {
+		name = DefaultTemplate
+	}
+	if !ValidTemplateName(name) {
+		return "", fmt.Errorf("%w: %q is not a valid template name", ErrTemplateNotFound, name)
+	}
+	return r.templateSource(ctx, name, format)
+}
+
+// RenderSource writes resume to w in format, with source, the template name
+// returned by TemplateSource, for Markdown and HTML
+func RenderSource(w io.Writer, format Format, name string, source string, resume *Resume) error {
+	if format == PDF {
+		return renderPDF(w, resume)
+	}
+	if name == "" {
+		name = DefaultTemplate
+	}
+
+	var tmpl interface {
+		Execute(w io.Writer, data any) error
+	}
+	var err error
+	if format == HTML {
+		tmpl, err = htmltemplate.New(name).Funcs(templateFuncs).Parse(source)
+	} else {
+		tmpl, err = texttemplate.New(name).Funcs(templateFuncs).Parse(source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse resume template %s: %w", name, err)
+	}
+
+	// templates that fail part way through write nothing
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, resume); err != nil {
+		return fmt.Errorf("failed to render resume template %s: %w", name, err)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// templateSource returns the uploaded template name for format, or the
+// embedded one if there is no upload
+func (r *Renderer) templateSource(ctx context.Context, name string, format Format) (string, error) {
+	if r.Store != nil {
+		content, err := r.Store.GetFile(ctx, TemplateKey(name, format))
+		if err != nil {
+			return "", fmt.Errorf("failed to load resume template %s: %w", name, err)
+		}
+		if content != nil {
+			return string(content), nil
+		}
+	}
+
+	content, err := embeddedTemplates.ReadFile("templates/" + name + "." + string(format) + ".tmpl")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s for %s", ErrTemplateNotFound, name, format)
+	}
+	return string(content), nil
+}
+
+// functions templates can call
+var templateFuncs = map[string]any{
+	"join":     strings.Join,
+	"period":   period,
+	"date":     displayDate,
+	"location": location,
+}
+
+// period returns the dates of work or a project for display, e.g.
+// Jun 2019 - Present
+func period(startDate string, endDate string) string {
+	start, end := displayDate(startDate), displayDate(endDate)
+	switch {
+	case start == "":
+		return end
+	case end == "" || end == start:
+		return start
+	}
+	return start + " - " + end
+}
+
+// displayDate returns date as the month and year, or only the year if that is
+// all it has. Dates that are not known layouts, such as Present, are returned
+// as they are.
+func displayDate(date string) string {
+	start, end, ok := models.ParseDate(date)
+	if !ok {
+		return strings.TrimSpace(date)
+	}
+	if end.Sub(start) > 31*24*time.Hour {
+		return start.Format("2006")
+	}
+	return start.Format("Jan 2006")
+}
+
+func location(location models.Location) string {
+	var parts []string
+	for _, part := range []string{location.City, location.State} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
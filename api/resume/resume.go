@@ -0,0 +1,146 @@
+// Package resume renders a resume from the work, skillsTools and projects of
+// the portfolio as Markdown, HTML or PDF.
+package resume
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// sections of a resume, in the order they are rendered
+const (
+	SectionWork     = "work"
+	SectionSkills   = "skills"
+	SectionProjects = "projects"
+)
+
+var Sections = []string{SectionWork, SectionSkills, SectionProjects}
+
+// DefaultTitle is the title of a resume when Options does not set one
+const DefaultTitle = "Resume"
+
+// ErrInvalidOptions is returned for options that select sections or projects
+// that do not exist
+var ErrInvalidOptions = errors.New("invalid resume options")
+
+type Options struct {
+	Title string
+	// Sections to include, every section when empty
+	Sections []string
+	// From and To limit work and projects to those that overlap the window
+	// from From up to but not including To, see models.ParseDate. Either can
+	// be zero to leave that side open.
+	From time.Time
+	To   time.Time
+	// Projects to include by sortValue or name, every project when empty
+	Projects []string
+}
+
+// Resume is the content a resume is rendered from
+type Resume struct {
+	Title       string
+	Sections    []string
+	Work        []models.Work
+	SkillsTools []models.SkillsTools
+	Projects    []models.Project
+}
+
+// New selects the content of a resume from portfolio
+func New(portfolio models.Portfolio, opts Options) (*Resume, error) {
+	resume := &Resume{
+		Title:    opts.Title,
+		Sections: Sections,
+	}
+	if resume.Title == "" {
+		resume.Title = DefaultTitle
+	}
+
+	if len(opts.Sections) > 0 {
+		resume.Sections = nil
+		for _, section := range Sections {
+			if slices.Contains(opts.Sections, section) {
+				resume.Sections = append(resume.Sections, section)
+			}
+		}
+		for _, section := range opts.Sections {
+			if !slices.Contains(Sections, section) {
+				return nil, fmt.Errorf("%w: unknown section %q, expected one of %s", ErrInvalidOptions, section, strings.Join(Sections, ", "))
+			}
+		}
+	}
+
+	if resume.Has(SectionWork) {
+		for _, work := range portfolio.Work {
+			if opts.inWindow(work.StartDate, work.EndDate) {
+				resume.Work = append(resume.Work, work)
+			}
+		}
+	}
+	if resume.Has(SectionSkills) {
+		resume.SkillsTools = portfolio.SkillsTools
+	}
+	if resume.Has(SectionProjects) {
+		projects, err := selectProjects(portfolio.Projects, opts.Projects)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			if opts.inWindow(project.StartDate, project.EndDate) {
+				resume.Projects = append(resume.Projects, project)
+			}
+		}
+	}
+
+	return resume, nil
+}
+
+// Has reports whether the resume includes section
+func (r *Resume) Has(section string) bool {
+	return slices.Contains(r.Sections, section)
+}
+
+// selectProjects returns the projects whose sortValue or name is in names,
+// in the order they are stored, or every project when names is empty
+func selectProjects(projects []models.Project, names []string) ([]models.Project, error) {
+	if len(names) == 0 {
+		return projects, nil
+	}
+
+	var selected []models.Project
+	found := make([]bool, len(names))
+	for _, project := range projects {
+		matched := false
+		for i, name := range names {
+			if strings.EqualFold(name, project.SortValue) || strings.EqualFold(name, project.Name) {
+				found[i], matched = true, true
+			}
+		}
+		if matched {
+			selected = append(selected, project)
+		}
+	}
+	for i, name := range names {
+		if !found[i] {
+			return nil, fmt.Errorf("%w: unknown project %q", ErrInvalidOptions, name)
+		}
+	}
+	return selected, nil
+}
+
+// inWindow reports whether the period from startDate to endDate overlaps the
+// window of opts. Dates that cannot be parsed leave that side of the period
+// open, so ongoing work with an end date of Present is always current.
+func (opts Options) inWindow(startDate string, endDate string) bool {
+	if start, _, ok := models.ParseDate(startDate); ok && !opts.To.IsZero() && !start.Before(opts.To) {
+		return false
+	}
+	if _, end, ok := models.ParseDate(endDate); ok && !opts.From.IsZero() && !end.After(opts.From) {
+		return false
+	}
+	return true
+}
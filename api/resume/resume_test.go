@@ -0,0 +1,286 @@
+package resume
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func testPortfolio() models.Portfolio {
+	link := "https://github.com/thomasmendez/personal-website-backend"
+	return models.Portfolio{
+		Work: []models.Work{
+			{
+				SortValue:      "2019-06-11",
+				JobTitle:       "Software Engineer",
+				Company:        "New Company",
+				Location:       models.Location{City: "New York", State: "NY"},
+				StartDate:      "2019-06-11",
+				EndDate:        "2020-12-31",
+				JobDescription: []string{"Developed backend systems", "Optimized database queries"},
+			},
+			{
+				SortValue: "2021-01-04",
+				JobTitle:  "Senior Engineer",
+				Company:   "Other Company",
+				StartDate: "2021-01-04",
+				EndDate:   "Present",
+			},
+		},
+		SkillsTools: []models.SkillsTools{{Categories: []models.Category{
+			{Category: "Languages", List: []string{"Go", "TypeScript"}},
+		}}},
+		Projects: []models.Project{
+			{
+				SortValue:   "Personal Website",
+				Name:        "Personal Website",
+				Description: "Website <and> API",
+				Role:        "Full Stack Developer",
+				Tasks:       []string{"Develop backend microservices"},
+				Tools:       []string{"Go", "React"},
+				StartDate:   "Jan 2024",
+				EndDate:     "Dec 2024",
+				Link:        &link,
+			},
+			{
+				SortValue: "Game",
+				Name:      "Game",
+				StartDate: "2018",
+				EndDate:   "2018",
+			},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	date := func(value string) time.Time {
+		start, _, _ := models.ParseDate(value)
+		return start
+	}
+
+	for _, test := range []struct {
+		label            string
+		opts             Options
+		expectedSections []string
+		expectedWork     []string
+		expectedProjects []string
+		expectError      bool
+	}{
+		{
+			label:            "Everything",
+			expectedSections: Sections,
+			expectedWork:     []string{"2019-06-11", "2021-01-04"},
+			expectedProjects: []string{"Personal Website", "Game"},
+		},
+		{
+			label:            "Sections in render order",
+			opts:             Options{Sections: []string{"projects", "work"}},
+			expectedSections: []string{SectionWork, SectionProjects},
+			expectedWork:     []string{"2019-06-11", "2021-01-04"},
+			expectedProjects: []string{"Personal Website", "Game"},
+		},
+		{
+			label:            "From a date",
+			opts:             Options{From: date("2021-01")},
+			expectedSections: Sections,
+			expectedWork:     []string{"2021-01-04"},
+			expectedProjects: []string{"Personal Website"},
+		},
+		{
+			label:            "Up to a date",
+			opts:             Options{To: date("2019")},
+			expectedSections: Sections,
+			expectedProjects: []string{"Game"},
+		},
+		{
+			label:            "Ending in the window",
+			opts:             Options{From: date("2020-12-31"), To: date("2021-01-01")},
+			expectedSections: Sections,
+			expectedWork:     []string{"2019-06-11"},
+		},
+		{
+			label:            "Selected projects",
+			opts:             Options{Projects: []string{"game"}},
+			expectedSections: Sections,
+			expectedWork:     []string{"2019-06-11", "2021-01-04"},
+			expectedProjects: []string{"Game"},
+		},
+		{
+			label:       "Unknown section",
+			opts:        Options{Sections: []string{"education"}},
+			expectError: true,
+		},
+		{
+			label:       "Unknown project",
+			opts:        Options{Projects: []string{"Other"}},
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			resume, err := New(testPortfolio(), test.opts)
+			if test.expectError {
+				if !errors.Is(err, ErrInvalidOptions) {
+					t.Errorf("expected ErrInvalidOptions, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(resume.Sections, ",") != strings.Join(test.expectedSections, ",") {
+				t.Errorf("expected sections %v, got %v", test.expectedSections, resume.Sections)
+			}
+			var work, projects []string
+			for _, w := range resume.Work {
+				work = append(work, w.SortValue)
+			}
+			for _, p := range resume.Projects {
+				projects = append(projects, p.SortValue)
+			}
+			if strings.Join(work, ",") != strings.Join(test.expectedWork, ",") {
+				t.Errorf("expected work %v, got %v", test.expectedWork, work)
+			}
+			if strings.Join(projects, ",") != strings.Join(test.expectedProjects, ",") {
+				t.Errorf("expected projects %v, got %v", test.expectedProjects, projects)
+			}
+		})
+	}
+}
+
+type fakeTemplateStore map[string]string
+
+func (f fakeTemplateStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	if content, ok := f[key]; ok {
+		return []byte(content), nil
+	}
+	return nil, nil
+}
+
+func TestRender(t *testing.T) {
+	resume, err := New(testPortfolio(), Options{Title: "Thomas Mendez", Projects: []string{"Personal Website"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := &Renderer{Store: fakeTemplateStore{
+		TemplateKey("short", Markdown): "{{.Title}}: {{len .Work}} jobs",
+	}}
+
+	for _, test := range []struct {
+		label       string
+		format      Format
+		template    string
+		expected    string
+		contains    []string
+		expectError error
+	}{
+		{
+			label:  "Markdown",
+			format: Markdown,
+			expected: `# Thomas Mendez
+
+## Work Experience
+
+### Software Engineer, New Company
+
+Jun 2019 - Dec 2020 | New York, NY
+
+- Developed backend systems
+- Optimized database queries
+
+### Senior Engineer, Other Company
+
+Jan 2021 - Present
+
+## Skills and Tools
+
+- **Languages:** Go, TypeScript
+
+## Projects
+
+### Personal Website, Full Stack Developer
+
+Jan 2024 - Dec 2024 | <https://github.com/thomasmendez/personal-website-backend>
+
+Website <and> API
+
+- Develop backend microservices
+
+**Tools:** Go, React
+`,
+		},
+		{
+			label:    "HTML is escaped",
+			format:   HTML,
+			contains: []string{"<title>Thomas Mendez</title>", "<p>Website &lt;and&gt; API</p>", `<a href="https://github.com/thomasmendez/personal-website-backend">`},
+		},
+		{
+			label:    "Uploaded template",
+			format:   Markdown,
+			template: "short",
+			expected: "Thomas Mendez: 2 jobs",
+		},
+		{
+			label:       "Missing template",
+			format:      HTML,
+			template:    "short",
+			expectError: ErrTemplateNotFound,
+		},
+		{
+			label:       "Invalid template name",
+			format:      Markdown,
+			template:    "../default",
+			expectError: ErrTemplateNotFound,
+		},
+		{
+			label:    "PDF",
+			format:   PDF,
+			contains: []string{"%PDF-", "/Title"},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := renderer.Render(context.Background(), &buf, test.format, test.template, resume)
+			if test.expectError != nil {
+				if !errors.Is(err, test.expectError) {
+					t.Errorf("expected %v, got %v", test.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expected != "" && buf.String() != test.expected {
+				t.Errorf("expected\n%s\ngot\n%s", test.expected, buf.String())
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("expected output to contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPDFIsStable(t *testing.T) {
+	resume, err := New(testPortfolio(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := &Renderer{}
+
+	var first, second bytes.Buffer
+	if err := renderer.Render(context.Background(), &first, PDF, "", resume); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderer.Render(context.Background(), &second, PDF, "", resume); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("expected the same resume to render the same PDF")
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/archive"
+)
+
+const (
+	mediaTypeZip = "application/zip"
+	// the largest response Lambda returns, larger archives must be exported
+	// with cmd/archive
+	maxResponseBodySize = 6 << 20
+)
+
+// exportHandler returns a zip archive of every work, skillsTools and project
+// item, see the archive package. With the media query parameter set to true
+// the archive includes the media the items reference.
+func (s *Service) exportHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	withMedia, err := queryBool(request.QueryStringParameters, "media")
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+
+	var body bytes.Buffer
+	manifest, err := archive.Export(ctx, s.DB, s.S3, s.TableName, &body, withMedia)
+	if err != nil {
+		log.Printf("error in exporting archive: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(body.Bytes())
+	if len(encoded) > maxResponseBodySize {
+		return mediaErrorResponse(http.StatusInternalServerError, "archive of %d bytes is too large to return, export it with cmd/archive", body.Len()), nil
+	}
+	filename := fmt.Sprintf("portfolio-%s.zip", manifest.ExportedAt.Format("2006-01-02"))
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        mediaTypeZip,
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+		},
+		Body:            encoded,
+		IsBase64Encoded: true,
+	}, nil
+}
+
+// importHandler imports the zip archive in the body, written by
+// exportHandler or cmd/archive, and returns the report of the import. With
+// the dryRun query parameter set to true it only reports the differences
+// between the archive and the table.
+func (s *Service) importHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	dryRun, err := queryBool(request.QueryStringParameters, "dryRun")
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+
+	mediaType, _, err := requestMediaType(request)
+	if err == nil && mediaType != mediaTypeZip && mediaType != "application/octet-stream" {
+		err = fmt.Errorf("%w: %s, expected %s", errUnsupportedMediaType, mediaType, mediaTypeZip)
+	}
+	if err == nil && requestBodySize(request) > s.Uploads.MaxBodySize {
+		err = fmt.Errorf("%w: %d bytes is over the limit of %d bytes", errBodyTooLarge, requestBodySize(request), s.Uploads.MaxBodySize)
+	}
+	if err != nil {
+		return uploadErrorResponse(err), nil
+	}
+
+	content, err := io.ReadAll(requestBodyReader(request))
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "invalid body: %v", err), nil
+	}
+	a, err := archive.Read(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+
+	report, err := archive.Import(ctx, s.DB, s.S3, s.TableName, a, archive.ImportOptions{
+		DryRun:              dryRun,
+		AllowedContentTypes: s.Uploads.AllowedContentTypes,
+		MaxMediaSize:        s.Uploads.MaxUploadSize,
+	})
+	if errors.Is(err, errUnsupportedMediaType) {
+		log.Printf("error in archive media: %v", err)
+		return mediaErrorResponse(http.StatusUnsupportedMediaType, "%v", err), nil
+	}
+	if errors.Is(err, archive.ErrInvalidArchive) {
+		log.Printf("error in archive media: %v", err)
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+	if err != nil {
+		log.Printf("error in importing archive: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	res, err := json.Marshal(report)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(res),
+	}, nil
+}
+
+// queryBool parses the query parameter name as a bool, false when it is not set
+func queryBool(params map[string]string, name string) (bool, error) {
+	value, ok := params[name]
+	if !ok || value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New(name + " must be true or false")
+	}
+	return b, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestImportHandlerRejectsRequests(t *testing.T) {
+	s := &Service{Uploads: &UploadConfig{MaxBodySize: 16}}
+
+	for _, test := range []struct {
+		label          string
+		contentType    string
+		params         map[string]string
+		body           string
+		expectedStatus int
+	}{
+		{
+			label:          "Invalid dryRun",
+			contentType:    mediaTypeZip,
+			params:         map[string]string{"dryRun": "maybe"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			label:          "JSON body",
+			contentType:    "application/json",
+			body:           "{}",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:          "Too large",
+			contentType:    mediaTypeZip,
+			body:           "a zip file over sixteen bytes",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			label:          "Not a zip file",
+			contentType:    "application/octet-stream",
+			body:           "not a zip file",
+			expectedStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			res, err := s.importHandler(context.Background(), events.APIGatewayProxyRequest{
+				Headers:               map[string]string{"Content-Type": test.contentType},
+				QueryStringParameters: test.params,
+				Body:                  test.body,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.StatusCode != test.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", test.expectedStatus, res.StatusCode, res.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+)
+
+// default max-age of cacheable GET responses
+const defaultCacheMaxAge = 5 * time.Minute
+
+type CacheConfig struct {
+	MaxAge time.Duration
+}
+
+// newCacheConfig reads the max-age of GET responses in seconds from CACHE_MAX_AGE
+func newCacheConfig() *CacheConfig {
+	cache := &CacheConfig{MaxAge: defaultCacheMaxAge}
+
+	if maxAge := os.Getenv("CACHE_MAX_AGE"); maxAge != "" {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds < 0 {
+			log.Fatalf("error in configuration: CACHE_MAX_AGE must be a positive number of seconds \n Currently: %v", maxAge)
+		}
+		cache.MaxAge = time.Duration(seconds) * time.Second
+	}
+
+	return cache
+}
+
+// maxAgeUntil limits the max-age so a response containing presigned URLs is
+// not cached past refresh, the point the first of the URLs is regenerated
+func (c *CacheConfig) maxAgeUntil(refresh time.Time) time.Duration {
+	if refresh.IsZero() {
+		return c.MaxAge
+	}
+	return min(c.MaxAge, bucket.PresignedURLCacheTTL(refresh))
+}
+
+// cacheableResponse returns body, encoded as contentType, with ETag,
+// Cache-Control and Vary headers, or a 304 Not Modified response when the
+// request's If-None-Match header shows the client already has etag. A
+// request whose If-Match header does not match etag gets a 412
+// Precondition Failed response, and responses to requests with an
+// Authorization header are only cached privately. There is no Last-Modified
+// header, as items do not record when they changed.
+func (c *CacheConfig) cacheableResponse(request events.APIGatewayProxyRequest, body []byte, contentType string, etag string, maxAge time.Duration) events.APIGatewayProxyResponse {
+	requestHeaders := headersOf(request)
+
+	visibility := "public"
+	if requestHeaders.get("Authorization") != "" {
+		visibility = "private"
+	}
+	headers := map[string]string{
+		"ETag":          etag,
+		"Cache-Control": fmt.Sprintf("%s, max-age=%d", visibility, int(maxAge.Seconds())),
+		"Vary":          "Accept",
+	}
+
+	if preconditionFailed(requestHeaders, etag) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusPreconditionFailed,
+			Headers:    headers,
+		}
+	}
+	if notModified(requestHeaders, etag) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotModified,
+			Headers:    headers,
+		}
+	}
+
+	headers["Content-Type"] = contentType
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       string(body),
+	}
+}
+
+// noStore marks res so it is not cached, e.g. a portfolio with a section that
+// failed, which would otherwise be served until its max-age even after the
+// section recovers
+func noStore(res events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	res.Headers["Cache-Control"] = "no-store"
+	return res
+}
+
+// preconditionFailed reports whether the If-Match header of a request lists
+// neither * nor etag. If-Match uses strong comparison, so weak tags never match.
+func preconditionFailed(headers requestHeaders, etag string) bool {
+	ifMatch := headers.list("If-Match")
+	if len(ifMatch) == 0 {
+		return false
+	}
+	for _, tag := range ifMatch {
+		if tag == "*" || tag == etag {
+			return false
+		}
+	}
+	return true
+}
+
+func notModified(headers requestHeaders, etag string) bool {
+	for _, tag := range headers.list("If-None-Match") {
+		tag = strings.TrimPrefix(tag, "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// contentETag returns the ETag of the representation in contentType of
+// stored, the content as it is read from the table before presigned URLs are
+// added to it, whose URLs are regenerated at refresh. Every instance signs
+// URLs with its own credentials, so an ETag of the body would differ between
+// instances for the same content, but they all regenerate URLs at the same
+// times, so refresh changes the ETag once the URLs in a cached body are
+// replaced.
+func contentETag(contentType string, refresh time.Time, stored ...any) string {
+	hash := sha256.New()
+	hash.Write([]byte(contentType))
+	if !refresh.IsZero() {
+		hash.Write([]byte(refresh.UTC().Format(time.RFC3339Nano)))
+	}
+	encoder := json.NewEncoder(hash)
+	for _, v := range stored {
+		// the content is encoded again for the body, which reports errors
+		_ = encoder.Encode(v)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/resume"
+)
+
+func TestCacheableResponse(t *testing.T) {
+	cache := &CacheConfig{MaxAge: time.Minute}
+	body := []byte(`[{"sortValue":"2020-01-01"}]`)
+	etag := contentETag(mediaTypeJSON, time.Time{}, body)
+
+	for _, test := range []struct {
+		label             string
+		headers           map[string]string
+		multiValueHeaders map[string][]string
+		expectedStatus    int
+		expectedPrivate   bool
+	}{
+		{
+			label:          "No conditional headers",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			label:          "Matching If-None-Match",
+			headers:        map[string]string{"If-None-Match": etag},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			label:          "Weak If-None-Match in list",
+			headers:        map[string]string{"if-none-match": `"other", W/` + etag},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			label:          "Stale If-None-Match",
+			headers:        map[string]string{"If-None-Match": `"other"`},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			label:          "If-Modified-Since is ignored",
+			headers:        map[string]string{"If-Modified-Since": time.Now().UTC().Format(http.TimeFormat)},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			label:             "If-None-Match over multiple values",
+			multiValueHeaders: map[string][]string{"If-None-Match": {`"other"`, etag}},
+			expectedStatus:    http.StatusNotModified,
+		},
+		{
+			label:          "Matching If-Match",
+			headers:        map[string]string{"If-Match": etag},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			label:          "Stale If-Match",
+			headers:        map[string]string{"if-match": `"other"`},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			label:          "Weak If-Match",
+			headers:        map[string]string{"If-Match": "W/" + etag},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			label:           "Authorized request",
+			headers:         map[string]string{"authorization": "Bearer token"},
+			expectedStatus:  http.StatusOK,
+			expectedPrivate: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{Path: "/api/v1/work", Headers: test.headers, MultiValueHeaders: test.multiValueHeaders}
+			res := cache.cacheableResponse(request, body, mediaTypeJSON, etag, cache.MaxAge)
+
+			if res.StatusCode != test.expectedStatus {
+				t.Errorf("expected status %v, got %v", test.expectedStatus, res.StatusCode)
+			}
+			if res.Headers["ETag"] != etag {
+				t.Errorf("expected etag %v, got %v", etag, res.Headers["ETag"])
+			}
+			if _, ok := res.Headers["Last-Modified"]; ok {
+				t.Errorf("expected no last modified, got %v", res.Headers["Last-Modified"])
+			}
+			expectedCacheControl := "public, max-age=60"
+			if test.expectedPrivate {
+				expectedCacheControl = "private, max-age=60"
+			}
+			if res.Headers["Cache-Control"] != expectedCacheControl {
+				t.Errorf("unexpected cache control: %v", res.Headers["Cache-Control"])
+			}
+			if res.StatusCode != http.StatusOK && res.Body != "" {
+				t.Errorf("expected empty body for %v response", res.StatusCode)
+			}
+		})
+	}
+}
+
+// instanceSigner signs URLs as a Lambda instance would, with its own
+// credentials, so every instance returns other URLs for the same objects
+type instanceSigner struct {
+	instance int
+}
+
+func (s *instanceSigner) SignURL(ctx context.Context, key string, expires time.Time) (string, error) {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s?X-Amz-Credential=instance-%d", testBucketName, key, s.instance), nil
+}
+
+func (s *instanceSigner) ObjectKey(signedURL string) (string, bool) {
+	key, ok := strings.CutPrefix(signedURL, "https://"+testBucketName+".s3.amazonaws.com/")
+	key, _, _ = strings.Cut(key, "?")
+	return key, ok
+}
+
+func TestETagAcrossInstances(t *testing.T) {
+	s, fake := newTestService(t)
+	s.Resume = &resume.Renderer{}
+	seedProject(t, s, fake)
+
+	for _, test := range []struct {
+		label   string
+		handler func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		path    string
+		// hasURLs is whether the body contains the signed URLs
+		hasURLs bool
+	}{
+		{label: "Projects", handler: s.getProjectsHandler, path: "/api/v1/projects", hasURLs: true},
+		{label: "Portfolio", handler: s.getPortfolioHandler, path: "/api/v1/portfolio", hasURLs: true},
+		{label: "Resume", handler: s.getResumeHandler, path: "/api/v1/resume"},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			var responses []events.APIGatewayProxyResponse
+			for instance := range 2 {
+				s.S3 = bucket.NewBucket(aws.Config{Region: "us-east-2"}, testBucketName)
+				s.S3.URLSigner = &instanceSigner{instance: instance}
+				res, err := test.handler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: test.path})
+				if err != nil || res.StatusCode != http.StatusOK {
+					t.Fatalf("expected 200, got %d %s: %v", res.StatusCode, res.Body, err)
+				}
+				responses = append(responses, res)
+			}
+
+			if test.hasURLs && responses[0].Body == responses[1].Body {
+				t.Fatalf("expected each instance to sign its own URLs, got %s", responses[0].Body)
+			}
+			if responses[0].Headers["ETag"] != responses[1].Headers["ETag"] {
+				t.Errorf("expected the same ETag from every instance, got %s and %s", responses[0].Headers["ETag"], responses[1].Headers["ETag"])
+			}
+		})
+	}
+}
+
+func TestETagChangesWhenURLsAreResigned(t *testing.T) {
+	s, fake := newTestService(t)
+	seedProject(t, s, fake)
+	// URLs are regenerated every 100ms
+	s.S3.URLSigner = &instanceSigner{}
+	s.S3.URLExpiry = 600 * time.Millisecond
+
+	first, err := s.getProjectsHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/v1/projects"})
+	if err != nil || first.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s: %v", first.StatusCode, first.Body, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	res, err := s.getProjectsHandler(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/api/v1/projects",
+		Headers:    map[string]string{"If-None-Match": first.Headers["ETag"]},
+	})
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with the re-signed URLs, got %d %s: %v", res.StatusCode, res.Body, err)
+	}
+	if res.Headers["ETag"] == first.Headers["ETag"] {
+		t.Errorf("expected the ETag to change once the URLs are re-signed, got %s", res.Headers["ETag"])
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type CorsConfig struct {
+	AllowedOrigins   []string
+	AllowHeaders     string
+	AllowMethods     string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// newCorsConfig builds the CORS configuration for the given ENV. ORIGIN holds a
+// comma separated allowlist of exact origins (https://example.com) and wildcard
+// subdomain patterns (https://*.example.com). Dev falls back to the local
+// frontend and Local allows any origin.
+func newCorsConfig(env string) *CorsConfig {
+	cors := &CorsConfig{
+		AllowHeaders: envOrDefault("HEADERS", "*"),
+		AllowMethods: envOrDefault("METHODS", "*"),
+	}
+
+	switch env {
+	case "Local":
+		cors.AllowedOrigins = []string{"*"}
+	case "Dev":
+		cors.AllowedOrigins = parseOrigins(envOrDefault("ORIGIN", "http://localhost:5173"))
+	default:
+		cors.AllowedOrigins = parseOrigins(os.Getenv("ORIGIN"))
+	}
+
+	if credentials := os.Getenv("CORS_ALLOW_CREDENTIALS"); credentials != "" {
+		allow, err := strconv.ParseBool(credentials)
+		if err != nil {
+			log.Fatalf("error in configuration: CORS_ALLOW_CREDENTIALS must be a boolean \n Currently: %v", credentials)
+		}
+		cors.AllowCredentials = allow
+	}
+
+	if maxAge := os.Getenv("CORS_MAX_AGE"); maxAge != "" {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds < 0 {
+			log.Fatalf("error in configuration: CORS_MAX_AGE must be a positive number of seconds \n Currently: %v", maxAge)
+		}
+		cors.MaxAge = seconds
+	}
+
+	return cors
+}
+
+// headers returns the CORS headers for a request from origin. An allowed origin
+// is echoed back, otherwise Access-Control-Allow-Origin is left out so the
+// browser blocks the response. preflight adds the Max-Age header.
+func (c *CorsConfig) headers(origin string, preflight bool) map[string]string {
+	headers := map[string]string{
+		"Access-Control-Allow-Headers": c.AllowHeaders,
+		"Access-Control-Allow-Methods": c.AllowMethods,
+	}
+
+	allowOrigin := c.allowOrigin(origin)
+	if allowOrigin != "*" {
+		headers["Vary"] = "Origin"
+	}
+	if allowOrigin == "" {
+		return headers
+	}
+
+	headers["Access-Control-Allow-Origin"] = allowOrigin
+	if c.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	if preflight && c.MaxAge > 0 {
+		headers["Access-Control-Max-Age"] = strconv.Itoa(c.MaxAge)
+	}
+	return headers
+}
+
+// allowOrigin returns the value for Access-Control-Allow-Origin, or an empty
+// string when the origin is not in the allowlist
+func (c *CorsConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			// browsers reject a wildcard origin on credentialed requests
+			if c.AllowCredentials && origin != "" {
+				return origin
+			}
+			return "*"
+		}
+		if origin != "" && originMatches(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// originMatches reports whether origin matches pattern. Patterns are either an
+// exact origin or use a leading "*." in the host to match any subdomain.
+func originMatches(pattern string, origin string) bool {
+	if strings.EqualFold(pattern, origin) {
+		return true
+	}
+
+	patternURL, err := url.Parse(pattern)
+	if err != nil || !strings.HasPrefix(patternURL.Host, "*.") {
+		return false
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Host == "" {
+		return false
+	}
+
+	if !strings.EqualFold(patternURL.Scheme, originURL.Scheme) || patternURL.Port() != originURL.Port() {
+		return false
+	}
+
+	suffix := strings.ToLower(strings.TrimPrefix(patternURL.Hostname(), "*"))
+	host := strings.ToLower(originURL.Hostname())
+	return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+}
+
+func parseOrigins(origins string) []string {
+	var allowed []string
+	for _, origin := range strings.Split(origins, ",") {
+		// SAM parameters are often quoted for API Gateway, e.g. "'*'"
+		origin = strings.Trim(strings.TrimSpace(origin), "'")
+		origin = strings.TrimSuffix(origin, "/")
+		if origin != "" {
+			allowed = append(allowed, origin)
+		}
+	}
+	return allowed
+}
+
+func envOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return strings.Trim(value, "'")
+	}
+	return defaultValue
+}
@@ -0,0 +1,93 @@
+package service
+
+import "testing"
+
+func TestCorsHeaders(t *testing.T) {
+	for _, test := range []struct {
+		label          string
+		cors           CorsConfig
+		origin         string
+		preflight      bool
+		expectedOrigin string
+		expectedVary   bool
+		expectedMaxAge string
+	}{
+		{
+			label:          "Exact origin is echoed",
+			cors:           CorsConfig{AllowedOrigins: []string{"https://example.com", "https://preview.example.dev"}},
+			origin:         "https://preview.example.dev",
+			expectedOrigin: "https://preview.example.dev",
+			expectedVary:   true,
+		},
+		{
+			label:          "Wildcard subdomain is echoed",
+			cors:           CorsConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			origin:         "https://pr-42.preview.example.com",
+			expectedOrigin: "https://pr-42.preview.example.com",
+			expectedVary:   true,
+		},
+		{
+			label:        "Wildcard subdomain does not match apex domain",
+			cors:         CorsConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			origin:       "https://example.com",
+			expectedVary: true,
+		},
+		{
+			label:        "Wildcard subdomain requires matching scheme",
+			cors:         CorsConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			origin:       "http://www.example.com",
+			expectedVary: true,
+		},
+		{
+			label:        "Wildcard subdomain does not match suffix of another domain",
+			cors:         CorsConfig{AllowedOrigins: []string{"https://*.example.com"}},
+			origin:       "https://www.notexample.com",
+			expectedVary: true,
+		},
+		{
+			label:        "Unknown origin is not allowed",
+			cors:         CorsConfig{AllowedOrigins: []string{"https://example.com"}},
+			origin:       "https://evil.com",
+			expectedVary: true,
+		},
+		{
+			label:          "Any origin without credentials",
+			cors:           CorsConfig{AllowedOrigins: []string{"*"}},
+			origin:         "https://example.com",
+			expectedOrigin: "*",
+		},
+		{
+			label:          "Any origin with credentials echoes origin",
+			cors:           CorsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			origin:         "https://example.com",
+			expectedOrigin: "https://example.com",
+			expectedVary:   true,
+		},
+		{
+			label:          "Preflight includes max age",
+			cors:           CorsConfig{AllowedOrigins: []string{"http://localhost:5173"}, MaxAge: 600},
+			origin:         "http://localhost:5173",
+			preflight:      true,
+			expectedOrigin: "http://localhost:5173",
+			expectedVary:   true,
+			expectedMaxAge: "600",
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			headers := test.cors.headers(test.origin, test.preflight)
+
+			if headers["Access-Control-Allow-Origin"] != test.expectedOrigin {
+				t.Errorf("expected origin %q, got %q", test.expectedOrigin, headers["Access-Control-Allow-Origin"])
+			}
+			if _, vary := headers["Vary"]; vary != test.expectedVary {
+				t.Errorf("expected vary %v, got %v", test.expectedVary, vary)
+			}
+			if headers["Access-Control-Max-Age"] != test.expectedMaxAge {
+				t.Errorf("expected max age %q, got %q", test.expectedMaxAge, headers["Access-Control-Max-Age"])
+			}
+			if test.cors.AllowCredentials && test.expectedOrigin != "" && headers["Access-Control-Allow-Credentials"] != "true" {
+				t.Errorf("expected credentials header to be set")
+			}
+		})
+	}
+}
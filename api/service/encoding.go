@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gopkg.in/yaml.v3"
+)
+
+// media types responses can be encoded in
+const (
+	mediaTypeJSON       = "application/json"
+	mediaTypeYAML       = "application/yaml"
+	mediaTypeJSONResume = "application/vnd.jsonresume+json"
+)
+
+// responseFormats are the media types every GET endpoint can respond with, in
+// order of preference when the Accept header ranks them equally
+var responseFormats = []string{mediaTypeJSON, mediaTypeYAML}
+
+// other names clients send for the media types of responseFormats
+var mediaTypeAliases = map[string]string{
+	"application/x-yaml": mediaTypeYAML,
+	"text/yaml":          mediaTypeYAML,
+	"text/x-yaml":        mediaTypeYAML,
+}
+
+var errNotAcceptable = errors.New("not acceptable")
+
+// negotiateFormat returns the media type of offered that the request's Accept
+// header ranks highest, or the first of offered without an Accept header.
+// Requests that accept none of offered return errNotAcceptable.
+func negotiateFormat(request events.APIGatewayProxyRequest, offered []string) (string, error) {
+	accept := headersOf(request).list("Accept")
+	if len(accept) == 0 {
+		return offered[0], nil
+	}
+
+	type acceptedRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []acceptedRange
+	for _, mediaRange := range accept {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		ranges = append(ranges, acceptedRange{mediaType, quality})
+	}
+
+	// the quality of a media type is that of the most specific range matching
+	// it, so application/yaml;q=0 is not overridden by */*
+	format, bestQuality := "", 0.0
+	for _, candidate := range offered {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			if match := mediaRangeMatch(r.mediaType, candidate); match > specificity {
+				quality, specificity = r.quality, match
+			}
+		}
+		if quality > bestQuality {
+			format, bestQuality = candidate, quality
+		}
+	}
+	if format == "" {
+		return "", fmt.Errorf("%w: %s, expected one of %s", errNotAcceptable, strings.Join(accept, ", "), strings.Join(offered, ", "))
+	}
+	return format, nil
+}
+
+// mediaRangeMatch returns how specifically mediaRange matches mediaType, 2 for
+// the same type, 1 for type/* and 0 for */*, or -1 if it does not match
+func mediaRangeMatch(mediaRange string, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+// encodeResponse encodes v in the media type of negotiateFormat. JSON Resume
+// is JSON, so v must already be in its format.
+func encodeResponse(mediaType string, v any) ([]byte, error) {
+	switch mediaType {
+	case mediaTypeJSON, mediaTypeJSONResume:
+		return json.Marshal(v)
+	case mediaTypeYAML:
+		return marshalYAML(v)
+	}
+	return nil, fmt.Errorf("no encoder for %s", mediaType)
+}
+
+// marshalYAML encodes v as block style YAML with the field names and order of
+// its JSON encoding, since models only have json tags
+func marshalYAML(v any) ([]byte, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so decoding it into a node keeps the order of the fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(jsonBytes, &node); err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// clearYAMLStyle resets the flow and quoted styles of JSON so nodes are
+// encoded in block style. Strings that would read as another type stay quoted.
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// notAcceptableResponse is returned when negotiateFormat fails
+func notAcceptableResponse(err error) events.APIGatewayProxyResponse {
+	return mediaErrorResponse(http.StatusNotAcceptable, "%v", err)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	for _, test := range []struct {
+		label          string
+		accept         string
+		offered        []string
+		expectedFormat string
+	}{
+		{
+			label:          "No Accept header",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeJSON,
+		},
+		{
+			label:          "Any media type",
+			accept:         "*/*",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeJSON,
+		},
+		{
+			label:          "YAML",
+			accept:         "application/yaml",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeYAML,
+		},
+		{
+			label:          "YAML alias",
+			accept:         "text/x-yaml, */*;q=0.1",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeYAML,
+		},
+		{
+			label:          "Highest quality",
+			accept:         "application/json;q=0.5, application/yaml;q=0.9",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeYAML,
+		},
+		{
+			label:          "Most specific range decides the quality",
+			accept:         "application/json;q=0, */*",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeYAML,
+		},
+		{
+			label:          "Type wildcard",
+			accept:         "text/html, application/*;q=0.8",
+			offered:        responseFormats,
+			expectedFormat: mediaTypeJSON,
+		},
+		{
+			label:          "JSON Resume",
+			accept:         "application/vnd.jsonresume+json",
+			offered:        portfolioFormats,
+			expectedFormat: mediaTypeJSONResume,
+		},
+		{
+			label:   "JSON Resume is only offered for the portfolio",
+			accept:  "application/vnd.jsonresume+json",
+			offered: responseFormats,
+		},
+		{
+			label:   "Unsupported media type",
+			accept:  "text/html",
+			offered: responseFormats,
+		},
+		{
+			label:   "Every offered type refused",
+			accept:  "application/json;q=0, application/yaml;q=0",
+			offered: responseFormats,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{Headers: map[string]string{}}
+			if test.accept != "" {
+				request.Headers["accept"] = test.accept
+			}
+			format, err := negotiateFormat(request, test.offered)
+			if test.expectedFormat == "" {
+				if !errors.Is(err, errNotAcceptable) {
+					t.Errorf("expected errNotAcceptable, got %v (%v)", err, format)
+				}
+				if status := notAcceptableResponse(err).StatusCode; status != http.StatusNotAcceptable {
+					t.Errorf("expected status 406, got %v", status)
+				}
+				return
+			}
+			if err != nil || format != test.expectedFormat {
+				t.Errorf("expected %v, got %v (%v)", test.expectedFormat, format, err)
+			}
+		})
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	notes := "true"
+	projects := []models.Project{{
+		SortValue: "Personal Website",
+		Name:      "Personal Website: API",
+		Tasks:     []string{"Develop backend microservices"},
+		TeamSize:  nil,
+		Notes:     &notes,
+	}}
+
+	body, err := encodeResponse(mediaTypeYAML, projects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `- personalWebsiteType: ""
+  sortValue: Personal Website
+  category: ""
+  name: 'Personal Website: API'
+  description: ""
+  featuresDescription: ""
+  role: ""
+  tasks:
+    - Develop backend microservices
+  teamSize: null
+  teamRoles: null
+  cloudServices: null
+  tools: null
+  duration: ""
+  startDate: ""
+  endDate: ""
+  notes: "true"
+  link: null
+  linkType: null
+  mediaLink: null
+  mediaVariants: null
+  mediaPoster: null
+  mediaPosterTime: null
+  media: null
+`
+	if string(body) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, body)
+	}
+}
+
+func TestPortfolioJSONResume(t *testing.T) {
+	link := "https://github.com/thomasmendez/personal-website-backend"
+	cloudServices := []string{"Lambda"}
+	portfolio := models.Portfolio{
+		Work: []models.Work{{
+			JobTitle:       "Software Engineer",
+			Company:        "New Company",
+			Location:       models.Location{City: "New York", State: "NY"},
+			StartDate:      "2019-06-11",
+			EndDate:        "Present",
+			JobRole:        "Backend Developer",
+			JobDescription: []string{"Developed backend systems"},
+		}},
+		SkillsTools: []models.SkillsTools{{Categories: []models.Category{
+			{Category: "Languages", List: []string{"Go"}},
+			{Category: "Cloud", List: []string{"AWS"}},
+		}}},
+		Projects: []models.Project{{
+			Category:      "Software Engineering",
+			Name:          "Personal Website",
+			Role:          "Full Stack Developer",
+			Tasks:         []string{"Develop backend microservices"},
+			Tools:         []string{"Go"},
+			CloudServices: &cloudServices,
+			StartDate:     "Jan 2024",
+			EndDate:       "Dec 2024",
+			Link:          &link,
+		}},
+	}
+
+	body, err := encodeResponse(mediaTypeJSONResume, portfolio.JSONResume())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"$schema":"` + models.JSONResumeSchema + `",` +
+		`"work":[{"name":"New Company","position":"Software Engineer","location":"New York, NY","startDate":"2019-06-11","summary":"Backend Developer","highlights":["Developed backend systems"]}],` +
+		`"skills":[{"name":"Languages","keywords":["Go"]},{"name":"Cloud","keywords":["AWS"]}],` +
+		`"projects":[{"name":"Personal Website","highlights":["Develop backend microservices"],"keywords":["Go","Lambda"],"startDate":"2024-01","endDate":"2024-12","url":"` + link + `","roles":["Full Stack Developer"],"type":"Software Engineering"}]}`
+	if string(body) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, body)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/reconcile"
+)
+
+// HandleEvent routes the raw Lambda event to HandleRoute for API Gateway
+// requests, or runs the media reconciliation for EventBridge scheduled events
+func (s *Service) HandleEvent(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	var scheduledEvent events.CloudWatchEvent
+	if err := json.Unmarshal(event, &scheduledEvent); err == nil &&
+		scheduledEvent.Source == "aws.events" && scheduledEvent.DetailType == "Scheduled Event" {
+		return s.reconcileMedia(ctx)
+	}
+
+	var request events.APIGatewayProxyRequest
+	if err := json.Unmarshal(event, &request); err != nil {
+		return nil, fmt.Errorf("error in deserializing event: %w", err)
+	}
+	return s.HandleRoute(ctx, request)
+}
+
+// reconcileMedia reports S3 objects that no project references. Orphans are
+// only deleted when RECONCILE_DELETE is true.
+func (s *Service) reconcileMedia(ctx context.Context) (reconcile.Report, error) {
+	deleteOrphans, _ := strconv.ParseBool(os.Getenv("RECONCILE_DELETE"))
+
+	report, err := reconcile.Run(ctx, s.DB, s.S3, s.TableName, reconcile.Options{
+		Delete: deleteOrphans,
+		MinAge: reconcile.DefaultMinAge,
+	})
+	if err != nil {
+		log.Printf("error in reconciling media: %v", err)
+		return report, err
+	}
+
+	log.Printf("reconciled media: %d objects, %d projects, %d orphans, %d deleted, %d projects with missing media",
+		report.Objects, report.Projects, len(report.Orphans), len(report.Deleted), len(report.MissingMedia))
+	for _, orphan := range report.Orphans {
+		log.Printf("orphaned object: %s", orphan)
+	}
+	for _, missing := range report.MissingMedia {
+		log.Printf("project %s references missing object: %s", missing.SortValue, missing.Key)
+	}
+	return report, nil
+}
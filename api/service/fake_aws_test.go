@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+)
+
+const (
+	testTableName  = "PersonalWebsiteTable"
+	testBucketName = "personal-website-test"
+)
+
+// fakeAWS serves the DynamoDB and S3 requests of the service from memory, so
+// handlers can be tested with the real clients
+type fakeAWS struct {
+	mu sync.Mutex
+	// items are the DynamoDB JSON of the items by personalWebsiteType and sortValue
+	items map[[2]string]map[string]json.RawMessage
+	// objects are the S3 objects by key
+	objects map[string]fakeS3Object
+	// failPut fails the S3 uploads of the keys it returns true for
+	failPut func(key string) bool
+	// failUpdate fails every DynamoDB UpdateItem
+	failUpdate bool
+	// failQuery fails the DynamoDB queries of the personalWebsiteTypes it
+	// returns true for
+	failQuery func(personalWebsiteType string) bool
+}
+
+type fakeS3Object struct {
+	content     []byte
+	contentType string
+	disposition string
+}
+
+// newTestService returns a service whose table and bucket are a fakeAWS
+func newTestService(t *testing.T) (*Service, *fakeAWS) {
+	t.Helper()
+	fake := &fakeAWS{
+		items:   make(map[[2]string]map[string]json.RawMessage),
+		objects: make(map[string]fakeS3Object),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:       "us-east-2",
+		Credentials:  aws.AnonymousCredentials{},
+		BaseEndpoint: aws.String(server.URL),
+		// checksums are only sent when required, so uploads are not aws-chunked
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	}
+	s := &Service{
+		DB:        database.NewDatabase(cfg),
+		S3:        bucket.NewBucket(cfg, testBucketName, func(options *s3.Options) { options.UsePathStyle = true }),
+		TableName: testTableName,
+		Cache:     &CacheConfig{MaxAge: defaultCacheMaxAge},
+		Uploads: &UploadConfig{
+			MaxBodySize:         defaultMaxBodySize,
+			MaxFileSize:         defaultMaxFileSize,
+			MaxUploadSize:       defaultMaxUploadSize,
+			MaxImageSize:        defaultMaxImageSize,
+			AllowedContentTypes: mediatype.Detectable,
+		},
+	}
+	return s, fake
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		f.serveDynamoDB(w, r, strings.TrimPrefix(target, "DynamoDB_20120810."))
+		return
+	}
+	f.serveS3(w, r)
+}
+
+type dynamoDBRequest struct {
+	Key                       map[string]json.RawMessage
+	Item                      map[string]json.RawMessage
+	UpdateExpression          string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]json.RawMessage
+}
+
+// setExpression matches the assignments of the SET expressions UpdateItem builds
+var setExpression = regexp.MustCompile(`(#\w+) = (:\w+)`)
+
+func (f *fakeAWS) serveDynamoDB(w http.ResponseWriter, r *http.Request, operation string) {
+	var request dynamoDBRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var response any = struct{}{}
+	switch operation {
+	case "GetItem":
+		if item, ok := f.items[itemKeyOf(request.Key)]; ok {
+			response = map[string]any{"Item": item}
+		}
+	case "PutItem":
+		f.items[itemKeyOf(request.Item)] = request.Item
+	case "UpdateItem":
+		if f.failUpdate {
+			dynamoDBError(w, "update failed")
+			return
+		}
+		key := itemKeyOf(request.Key)
+		item, ok := f.items[key]
+		if !ok {
+			item = request.Key
+		}
+		updated := make(map[string]json.RawMessage, len(item))
+		for name, value := range item {
+			updated[name] = value
+		}
+		for _, assignment := range setExpression.FindAllStringSubmatch(request.UpdateExpression, -1) {
+			updated[request.ExpressionAttributeNames[assignment[1]]] = request.ExpressionAttributeValues[assignment[2]]
+		}
+		f.items[key] = updated
+	case "DeleteItem":
+		delete(f.items, itemKeyOf(request.Key))
+	case "Query":
+		partitionKey := stringValue(request.ExpressionAttributeValues[":partitionKey"])
+		if f.failQuery != nil && f.failQuery(partitionKey) {
+			dynamoDBError(w, "query failed")
+			return
+		}
+		var keys [][2]string
+		for key := range f.items {
+			if key[0] == partitionKey {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i][1] < keys[j][1] })
+		items := make([]map[string]json.RawMessage, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, f.items[key])
+		}
+		response = map[string]any{"Items": items, "Count": len(items), "ScannedCount": len(items)}
+	default:
+		http.Error(w, "unsupported operation "+operation, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(response)
+}
+
+func dynamoDBError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `{"__type":"com.amazonaws.dynamodb.v20120810#ValidationException","message":%q}`, message)
+}
+
+func itemKeyOf(item map[string]json.RawMessage) [2]string {
+	return [2]string{stringValue(item["personalWebsiteType"]), stringValue(item["sortValue"])}
+}
+
+func stringValue(value json.RawMessage) string {
+	var s struct{ S string }
+	json.Unmarshal(value, &s)
+	return s.S
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Name        string
+	Prefix      string
+	KeyCount    int
+	MaxKeys     int
+	IsTruncated bool
+	Contents    []listBucketObject
+}
+
+type listBucketObject struct {
+	Key  string
+	Size int
+}
+
+func (f *fakeAWS) serveS3(w http.ResponseWriter, r *http.Request) {
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucketName != testBucketName {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		prefix := r.URL.Query().Get("prefix")
+		result := listBucketResult{Name: bucketName, Prefix: prefix, MaxKeys: 1000}
+		for objectKey, object := range f.objects {
+			if strings.HasPrefix(objectKey, prefix) {
+				result.Contents = append(result.Contents, listBucketObject{Key: objectKey, Size: len(object.content)})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		result.KeyCount = len(result.Contents)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	case key == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut:
+		if f.failPut != nil && f.failPut(key) {
+			s3Error(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		content, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeS3Object{
+			content:     content,
+			contentType: r.Header.Get("Content-Type"),
+			disposition: r.Header.Get("Content-Disposition"),
+		}
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodHead, r.Method == http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		content := object.content
+		status := http.StatusOK
+		if start, end, ok := parseRange(r.Header.Get("Range"), len(content)); ok {
+			content = content[start:end]
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(object.content)))
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Disposition", object.disposition)
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// parseRange returns the byte range of a Range header such as bytes=0-511
+func parseRange(header string, size int) (int, int, bool) {
+	first, last, ok := strings.Cut(strings.TrimPrefix(header, "bytes="), "-")
+	if header == "" || !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.Atoi(first)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, min(end+1, size), true
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
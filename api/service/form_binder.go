@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// largest index of a form name such as tasks[99], so a request cannot
+// allocate huge slices
+const maxFormIndex = 99
+
+var errUnknownFormField = errors.New("unknown form field")
+
+// formNameSegment is a field of a form name with an optional index, e.g. the
+// categories[0] of categories[0].list. index is -1 without an index.
+type formNameSegment struct {
+	name  string
+	index int
+}
+
+// parseFormName splits a dotted and indexed form name such as
+// categories[0].list or location.city into its segments
+func parseFormName(name string) ([]formNameSegment, error) {
+	if name == "" {
+		return nil, errors.New("form field has no name")
+	}
+
+	var segments []formNameSegment
+	for _, part := range strings.Split(name, ".") {
+		segment := formNameSegment{name: part, index: -1}
+		if open := strings.IndexByte(part, '['); open != -1 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid form field name %s", name)
+			}
+			index, err := strconv.Atoi(part[open+1 : len(part)-1])
+			if err != nil || index < 0 || index > maxFormIndex {
+				return nil, fmt.Errorf("invalid index in form field name %s", name)
+			}
+			segment = formNameSegment{name: part[:open], index: index}
+		}
+		if segment.name == "" {
+			return nil, fmt.Errorf("invalid form field name %s", name)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// formBinder binds form fields to a struct. Field names are resolved by the
+// form tag of a struct field, or its json name if it has none, so forms use
+// the same names as JSON bodies. Fields tagged form:"-" cannot be bound.
+type formBinder struct {
+	target reflect.Value
+	// strict reports fields that are not in the struct instead of skipping them
+	strict  bool
+	unknown []string
+}
+
+// newFormBinder returns a binder for target, a pointer to a struct
+func newFormBinder(target any, strict bool) *formBinder {
+	return &formBinder{
+		target: reflect.ValueOf(target).Elem(),
+		strict: strict,
+	}
+}
+
+// bind sets the field the form name resolves to. Each segment of the name is
+// matched with a struct field, pointers are allocated and slices grown as
+// needed. Names that do not resolve to a field are recorded as unknown.
+func (b *formBinder) bind(name string, value []byte) error {
+	field, err := b.resolve(name)
+	if errors.Is(err, errUnknownFormField) {
+		log.Printf("unknown form field: %s", name)
+		b.unknown = append(b.unknown, name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return setFieldValue(field, value)
+}
+
+// validate returns an error wrapping errUnknownFormField listing every unknown
+// field in strict mode
+func (b *formBinder) validate() error {
+	if !b.strict || len(b.unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errUnknownFormField, strings.Join(b.unknown, ", "))
+}
+
+func (b *formBinder) resolve(name string) (reflect.Value, error) {
+	segments, err := parseFormName(name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	field := b.target
+	for _, segment := range segments {
+		field = allocatePointer(field)
+		if field.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("form field %s: %s does not have fields", name, segment.name)
+		}
+		var ok bool
+		if field, ok = fieldByFormName(field, segment.name); !ok {
+			return reflect.Value{}, fmt.Errorf("%w: %s", errUnknownFormField, name)
+		}
+		if segment.index == -1 {
+			continue
+		}
+
+		field = allocatePointer(field)
+		if field.Kind() != reflect.Slice {
+			return reflect.Value{}, fmt.Errorf("form field %s: %s is not a list", name, segment.name)
+		}
+		if field.Len() <= segment.index {
+			grown := reflect.MakeSlice(field.Type(), segment.index+1, segment.index+1)
+			reflect.Copy(grown, field)
+			field.Set(grown)
+		}
+		field = field.Index(segment.index)
+	}
+	return field, nil
+}
+
+// fieldByFormName returns the exported field of v named name in its form or
+// json tag, or by its Go name if it has neither. Like encoding/json, an exact
+// match is preferred over a case insensitive one.
+func fieldByFormName(v reflect.Value, name string) (reflect.Value, bool) {
+	folded := -1
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		formName := formFieldName(structField)
+		if formName == "-" {
+			continue
+		}
+		if formName == name {
+			return v.Field(i), true
+		}
+		if folded == -1 && strings.EqualFold(formName, name) {
+			folded = i
+		}
+	}
+	if folded == -1 {
+		return reflect.Value{}, false
+	}
+	return v.Field(folded), true
+}
+
+func formFieldName(structField reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(structField.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return structField.Name
+}
+
+// allocatePointer returns the value v points to, allocating it if v is nil,
+// or v itself if it is not a pointer
+func allocatePointer(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Ptr {
+		return v
+	}
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	return v.Elem()
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestFormBinder(t *testing.T) {
+	teamSize := "2"
+	caption := "Home page"
+	posterTime := 1.5
+
+	for _, test := range []struct {
+		label           string
+		fields          [][2]string
+		strict          bool
+		expectedProject models.Project
+		expectedUnknown []string
+		expectError     bool
+	}{
+		{
+			label: "Fields by json name",
+			fields: [][2]string{
+				{"personalWebsiteType", "Projects"},
+				{"sortValue", "Personal Website"},
+				{"featuresDescription", "User is able to view my work"},
+			},
+			expectedProject: models.Project{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				FeaturesDescription: "User is able to view my work",
+			},
+		},
+		{
+			label:           "Case insensitive name",
+			fields:          [][2]string{{"SortValue", "Personal Website"}},
+			expectedProject: models.Project{SortValue: "Personal Website"},
+		},
+		{
+			label: "Lists and pointers",
+			fields: [][2]string{
+				{"tasks", "Develop backend microservices, Write tests"},
+				{"teamSize", "2"},
+				{"teamRoles", `["Frontend Developer", "Backend Developer"]`},
+				{"cloudServices[1]", "Lambda"},
+				{"mediaPosterTime", "1.5"},
+			},
+			expectedProject: models.Project{
+				Tasks:           []string{"Develop backend microservices", "Write tests"},
+				TeamSize:        &teamSize,
+				TeamRoles:       &[]string{"Frontend Developer", "Backend Developer"},
+				CloudServices:   &[]string{"", "Lambda"},
+				MediaPosterTime: &posterTime,
+			},
+		},
+		{
+			label: "Nested fields",
+			fields: [][2]string{
+				{"media[0].caption", "Home page"},
+				{"mediaPoster.width", "640"},
+			},
+			expectedProject: models.Project{
+				Media:       []models.MediaItem{{Caption: &caption}},
+				MediaPoster: &models.MediaVariant{Width: 640},
+			},
+		},
+		{
+			label: "Unknown fields are skipped",
+			fields: [][2]string{
+				{"sortValue", "Personal Website"},
+				{"title", "Personal Website"},
+				{"mediaRef", `{"storage":"s3","key":"other.png"}`},
+			},
+			expectedProject: models.Project{SortValue: "Personal Website"},
+			expectedUnknown: []string{"title", "mediaRef"},
+		},
+		{
+			label: "Unknown fields in strict mode",
+			fields: [][2]string{
+				{"sortValue", "Personal Website"},
+				{"media[0].title", "Home page"},
+			},
+			strict:      true,
+			expectError: true,
+		},
+		{
+			label:       "Empty name",
+			fields:      [][2]string{{"", "Personal Website"}},
+			expectError: true,
+		},
+		{
+			label:       "Invalid number",
+			fields:      [][2]string{{"mediaPoster.width", "wide"}},
+			expectError: true,
+		},
+		{
+			label:       "Index of a field that is not a list",
+			fields:      [][2]string{{"sortValue[0]", "Personal Website"}},
+			expectError: true,
+		},
+		{
+			label:       "Fields of a string",
+			fields:      [][2]string{{"name.first", "Personal Website"}},
+			expectError: true,
+		},
+		{
+			label:       "Index over the limit",
+			fields:      [][2]string{{"tasks[100]", "Develop backend microservices"}},
+			expectError: true,
+		},
+		{
+			label:       "Malformed index",
+			fields:      [][2]string{{"media[0.caption", "Home page"}},
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			var project models.Project
+			binder := newFormBinder(&project, test.strict)
+			var err error
+			for _, field := range test.fields {
+				if err = binder.bind(field[0], []byte(field[1])); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = binder.validate()
+			}
+
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(project, test.expectedProject) {
+				t.Errorf("expected %+v, got %+v", test.expectedProject, project)
+			}
+			if !reflect.DeepEqual(binder.unknown, test.expectedUnknown) {
+				t.Errorf("expected unknown fields %v, got %v", test.expectedUnknown, binder.unknown)
+			}
+		})
+	}
+}
+
+func TestFormBinderStructLists(t *testing.T) {
+	for _, test := range []struct {
+		label               string
+		fields              [][2]string
+		expectedSkillsTools models.SkillsTools
+	}{
+		{
+			label: "Indexed struct fields",
+			fields: [][2]string{
+				{"categories[0].category", "Languages"},
+				{"categories[0].list", "Go, TypeScript"},
+				{"categories[1].category", "Cloud"},
+				{"categories[1].list[1]", "Lambda"},
+			},
+			expectedSkillsTools: models.SkillsTools{Categories: []models.Category{
+				{Category: "Languages", List: []string{"Go", "TypeScript"}},
+				{Category: "Cloud", List: []string{"", "Lambda"}},
+			}},
+		},
+		{
+			label: "JSON encoded struct in a list",
+			fields: [][2]string{
+				{"categories[1]", `{"category":"Cloud","list":["S3"]}`},
+			},
+			expectedSkillsTools: models.SkillsTools{Categories: []models.Category{
+				{},
+				{Category: "Cloud", List: []string{"S3"}},
+			}},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			var skillsTools models.SkillsTools
+			binder := newFormBinder(&skillsTools, true)
+			for _, field := range test.fields {
+				if err := binder.bind(field[0], []byte(field[1])); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := binder.validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(skillsTools, test.expectedSkillsTools) {
+				t.Errorf("expected %+v, got %+v", test.expectedSkillsTools, skillsTools)
+			}
+		})
+	}
+}
+
+func TestFormBinderFormTag(t *testing.T) {
+	var target struct {
+		Title    string `json:"title" form:"name"`
+		Internal string `json:"internal" form:"-"`
+	}
+	binder := newFormBinder(&target, true)
+
+	if err := binder.bind("name", []byte("Personal Website")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Title != "Personal Website" {
+		t.Errorf("expected the form tag to bind name, got %+v", target)
+	}
+
+	for _, name := range []string{"title", "internal"} {
+		if err := binder.bind(name, []byte("value")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := binder.validate(); !errors.Is(err, errUnknownFormField) {
+		t.Errorf("expected errUnknownFormField, got %v", err)
+	}
+	if target.Title != "Personal Website" || target.Internal != "" {
+		t.Errorf("expected title and internal to be unknown, got %+v", target)
+	}
+}
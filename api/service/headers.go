@@ -0,0 +1,48 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// requestHeaders is a case-insensitive view of the headers of a request.
+// API Gateway sets Headers to the last value of each header and
+// MultiValueHeaders to all of them, so a header in both takes its values from
+// MultiValueHeaders. Tests and local events often only set Headers.
+type requestHeaders http.Header
+
+func headersOf(request events.APIGatewayProxyRequest) requestHeaders {
+	headers := make(http.Header, len(request.Headers))
+	for name, values := range request.MultiValueHeaders {
+		for _, value := range values {
+			headers.Add(name, value)
+		}
+	}
+	for name, value := range request.Headers {
+		if headers.Get(name) == "" {
+			headers.Set(name, value)
+		}
+	}
+	return requestHeaders(headers)
+}
+
+// get returns the first value of the header name, or "" if it is not set
+func (h requestHeaders) get(name string) string {
+	return http.Header(h).Get(name)
+}
+
+// list returns the elements of a header that holds a comma separated list,
+// such as Accept or If-None-Match, across every value of it
+func (h requestHeaders) list(name string) []string {
+	var elements []string
+	for _, value := range http.Header(h).Values(name) {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	for _, test := range []struct {
+		label             string
+		headers           map[string]string
+		multiValueHeaders map[string][]string
+		name              string
+		expectedValue     string
+		expectedList      []string
+	}{
+		{
+			label:         "Any casing of the name",
+			headers:       map[string]string{"content-type": "application/json"},
+			name:          "Content-Type",
+			expectedValue: "application/json",
+			expectedList:  []string{"application/json"},
+		},
+		{
+			label:         "Lower case lookup",
+			headers:       map[string]string{"Origin": "https://example.com"},
+			name:          "origin",
+			expectedValue: "https://example.com",
+			expectedList:  []string{"https://example.com"},
+		},
+		{
+			label:             "Multi value headers take precedence",
+			headers:           map[string]string{"Accept": "text/html"},
+			multiValueHeaders: map[string][]string{"accept": {"application/json", "text/html"}},
+			name:              "Accept",
+			expectedValue:     "application/json",
+			expectedList:      []string{"application/json", "text/html"},
+		},
+		{
+			label:             "Headers only in one of the maps",
+			headers:           map[string]string{"Authorization": "Bearer token"},
+			multiValueHeaders: map[string][]string{"If-Match": {`"a"`}},
+			name:              "authorization",
+			expectedValue:     "Bearer token",
+			expectedList:      []string{"Bearer token"},
+		},
+		{
+			label:         "Comma separated list",
+			headers:       map[string]string{"If-None-Match": ` "a", W/"b" ,,`},
+			name:          "If-None-Match",
+			expectedValue: ` "a", W/"b" ,,`,
+			expectedList:  []string{`"a"`, `W/"b"`},
+		},
+		{
+			label: "Missing header",
+			name:  "If-Match",
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			headers := headersOf(events.APIGatewayProxyRequest{Headers: test.headers, MultiValueHeaders: test.multiValueHeaders})
+			if value := headers.get(test.name); value != test.expectedValue {
+				t.Errorf("expected %q, got %q", test.expectedValue, value)
+			}
+			if list := headers.list(test.name); !reflect.DeepEqual(list, test.expectedList) {
+				t.Errorf("expected %q, got %q", test.expectedList, list)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+)
+
+// timeout for each dependency check of the readiness endpoint
+const readinessTimeout = 2 * time.Second
+
+const (
+	statusOk          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+type HealthResponse struct {
+	Status       string                      `json:"status"`
+	Version      string                      `json:"version"`
+	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
+}
+
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Message   string `json:"message,omitempty"`
+}
+
+// healthHandler reports that the function is running without checking any dependencies
+func (s *Service) healthHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	healthJson, err := json.Marshal(HealthResponse{
+		Status:  statusOk,
+		Version: s.Version.Version,
+	})
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(healthJson),
+	}, err
+}
+
+// readyHandler checks that the DynamoDB table and S3 bucket are reachable
+func (s *Service) readyHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	checks := map[string]func(ctx context.Context) (string, error){
+		"dynamodb": func(ctx context.Context) (string, error) {
+			tableStatus, err := database.GetTableStatus(ctx, s.DB.Client, s.TableName)
+			if err != nil {
+				return "", err
+			}
+			if tableStatus != "ACTIVE" {
+				return "table status is " + tableStatus, nil
+			}
+			return "", nil
+		},
+		"s3": func(ctx context.Context) (string, error) {
+			return "", s.S3.CheckBucketAccess(ctx)
+		},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	res := HealthResponse{
+		Status:       statusOk,
+		Version:      s.Version.Version,
+		Dependencies: make(map[string]DependencyStatus, len(checks)),
+	}
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) (string, error)) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+
+			start := time.Now()
+			message, err := check(checkCtx)
+			dependency := DependencyStatus{
+				Status:    statusOk,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Message:   message,
+			}
+			if err != nil {
+				log.Printf("readiness check for %s failed: %v", name, err)
+				dependency.Status = statusUnavailable
+				dependency.Message = "unable to reach " + name
+				if checkCtx.Err() == context.DeadlineExceeded {
+					dependency.Message = "timed out reaching " + name
+				}
+			} else if message != "" {
+				dependency.Status = statusUnavailable
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Dependencies[name] = dependency
+			if dependency.Status != statusOk {
+				res.Status = statusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	statusCode := http.StatusOK
+	if res.Status != statusOk {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	readyJson, err := json.Marshal(res)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(readyJson),
+	}, err
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+)
+
+const (
+	mediaURLModeS3  = "s3"
+	mediaURLModeCDN = "cdn"
+)
+
+// configureMediaURLs sets how the media URLs in responses are signed.
+// PRESIGN_EXPIRY is how long URLs are valid for in seconds. MEDIA_URL_MODE
+// selects S3 presigned URLs (s3, the default) or URLs under CDN_BASE_URL
+// (cdn), which are signed with CDN_KEY_PAIR_ID and CDN_PRIVATE_KEY when they
+// are set. Stored media are references to object keys, so the mode can be
+// changed without migrating items, and the URLs of the current mode and S3
+// URLs in requests are parsed back into references.
+func configureMediaURLs(b *bucket.Bucket) {
+	if expiry := os.Getenv("PRESIGN_EXPIRY"); expiry != "" {
+		seconds, err := strconv.Atoi(expiry)
+		// S3 presigned URLs are valid for at most 7 days
+		if err != nil || seconds < 60 || seconds > 7*24*60*60 {
+			log.Fatalf("error in configuration: PRESIGN_EXPIRY must be between 60 and 604800 seconds \n Currently: %v", expiry)
+		}
+		b.URLExpiry = time.Duration(seconds) * time.Second
+	}
+
+	switch mode := strings.ToLower(envOrDefault("MEDIA_URL_MODE", mediaURLModeS3)); mode {
+	case mediaURLModeS3:
+	case mediaURLModeCDN:
+		signer, err := bucket.NewCDNURLSigner(os.Getenv("CDN_BASE_URL"), os.Getenv("CDN_KEY_PAIR_ID"), cdnPrivateKey())
+		if err != nil {
+			log.Fatalf("error in configuration: MEDIA_URL_MODE cdn requires CDN_BASE_URL, and CDN_KEY_PAIR_ID with CDN_PRIVATE_KEY for signed URLs: %v", err)
+		}
+		b.URLSigner = signer
+	default:
+		log.Fatalf("error in configuration: MEDIA_URL_MODE must be 's3' or 'cdn' \n Currently: %v", mode)
+	}
+}
+
+// cdnPrivateKey returns CDN_PRIVATE_KEY, which is either PEM or base64
+// encoded PEM since multi line values are awkward in most environments
+func cdnPrivateKey() []byte {
+	key := strings.TrimSpace(os.Getenv("CDN_PRIVATE_KEY"))
+	if key == "" || strings.HasPrefix(key, "-----BEGIN") {
+		return []byte(key)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		log.Fatalf("error in configuration: CDN_PRIVATE_KEY must be PEM or base64 encoded PEM")
+	}
+	return decoded
+}
@@ -1,43 +1,46 @@
 package service
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"mime"
 	"mime/multipart"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
 	"github.com/thomasmendez/personal-website-backend/api/models"
 )
 
-// parse form data from request body
-func parseFormData[T any](request events.APIGatewayProxyRequest) (*T, models.FileData, error) {
-	var bodyBytes []byte
-	bodyBytes, err := base64.StdEncoding.DecodeString(request.Body)
-	if err != nil {
-		return nil, models.FileData{}, fmt.Errorf("failed to decode base64 body: %w", err)
+// parse form data from request body, returning the bound fields and every file
+// part. Bodies and files over the upload limits return errors wrapping
+// errBodyTooLarge or errFileTooLarge, and files whose content type is not
+// allowed return errUnsupportedMediaType.
+func parseFormData[T any](request events.APIGatewayProxyRequest, uploads *UploadConfig) (*T, []models.FileData, error) {
+	if size := requestBodySize(request); size > uploads.MaxBodySize {
+		return nil, nil, fmt.Errorf("%w: %d bytes is over the limit of %d bytes", errBodyTooLarge, size, uploads.MaxBodySize)
 	}
 
-	_, params, err := mime.ParseMediaType(getContentType(request.Headers))
+	mediaType, params, err := requestMediaType(request)
 	if err != nil {
-		return nil, models.FileData{}, fmt.Errorf("failed to parse content type: %w", err)
+		return nil, nil, err
+	}
+	if mediaType != formDataMediaType {
+		return nil, nil, fmt.Errorf("%w: expected %s, got %q", errUnsupportedMediaType, formDataMediaType, mediaType)
 	}
 
 	boundary := params["boundary"]
 	if boundary == "" {
-		return nil, models.FileData{}, fmt.Errorf("no boundary found in content type")
+		return nil, nil, fmt.Errorf("no boundary found in content type")
 	}
 
-	reader := multipart.NewReader(strings.NewReader(string(bodyBytes)), boundary)
+	reader := multipart.NewReader(requestBodyReader(request), boundary)
 
 	var result T
-	file := models.FileData{}
+	var files []models.FileData
+	binder := newFormBinder(&result, uploads.StrictFormFields)
 
 	for {
 		part, err := reader.NextPart()
@@ -45,13 +48,17 @@ func parseFormData[T any](request events.APIGatewayProxyRequest) (*T, models.Fil
 			break
 		}
 		if err != nil {
-			return nil, models.FileData{}, fmt.Errorf("failed to get next part: %w", err)
+			return nil, nil, fmt.Errorf("failed to get next part: %w", err)
 		}
 
-		content, err := io.ReadAll(part)
+		content, err := io.ReadAll(io.LimitReader(part, uploads.MaxFileSize+1))
 		if err != nil {
 			part.Close()
-			return nil, models.FileData{}, fmt.Errorf("failed to read part content: %w", err)
+			return nil, nil, fmt.Errorf("failed to read part content: %w", err)
+		}
+		if int64(len(content)) > uploads.MaxFileSize {
+			part.Close()
+			return nil, nil, fmt.Errorf("%w: part %s is over the limit of %d bytes", errFileTooLarge, part.FormName(), uploads.MaxFileSize)
 		}
 
 		fieldName := part.FormName()
@@ -59,52 +66,41 @@ func parseFormData[T any](request events.APIGatewayProxyRequest) (*T, models.Fil
 
 		if filename != "" {
 			if content == nil {
-				return nil, models.FileData{}, fmt.Errorf("file content is nil")
+				return nil, nil, fmt.Errorf("file content is nil")
 			}
-			contentType, err := detectContentType(content)
+			contentType, content, err := mediatype.Prepare(content, uploads.AllowedContentTypes)
 			if err != nil {
-				return nil, models.FileData{}, fmt.Errorf("failed to detect content type: %w", err)
+				return nil, nil, fmt.Errorf("file %s: %w", filename, err)
 			}
-			file = models.FileData{
+			files = append(files, models.FileData{
+				FieldName:   fieldName,
 				Filename:    filename,
 				Content:     content,
 				ContentType: contentType,
-			}
-			fmt.Printf("file: %s, filename: %s, contentType: %s\n", fieldName, filename, contentType)
+			})
 		} else {
-			log.Printf("fieldName: %s", fieldName)
-			log.Printf("content: %v", content)
-
-			// if filename is empty set the map of the result to nil
-			v := reflect.ValueOf(&result).Elem()
-			structName := strings.ToUpper(fieldName[:1]) + fieldName[1:]
-			field := v.FieldByName(structName)
-			if !field.CanSet() {
-				log.Printf("invalid or non-settable field: %s", fieldName)
-				continue // Skip invalid or non-settable fields
-			}
-			if err := setFieldValue(field, content); err != nil {
-				return nil, models.FileData{}, fmt.Errorf("failed to set field value: %w", err)
+			if err := binder.bind(fieldName, content); err != nil {
+				part.Close()
+				return nil, nil, fmt.Errorf("failed to set field value: %w", err)
 			}
 		}
 
 		part.Close()
 	}
-	return &result, file, nil
+	if err := binder.validate(); err != nil {
+		return nil, nil, err
+	}
+	return &result, files, nil
 }
 
 func setFieldValue(field reflect.Value, value []byte) error {
-	log.Printf("field kind: %v", field.Kind())
-	log.Printf("field type: %v", field.Type())
-	log.Printf("value: %v", value)
-
 	switch field.Kind() {
 	case reflect.String:
-		log.Printf("setting string field: %s", string(value))
 		field.SetString(string(value))
 	case reflect.Slice:
-		log.Printf("setting slice field: %v", value)
 		return setSliceFromBytes(field, value)
+	case reflect.Struct:
+		return setFromJSON(field, value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if intVal, err := strconv.ParseInt(string(value), 10, 64); err == nil {
 			field.SetInt(intVal)
@@ -124,6 +120,9 @@ func setFieldValue(field reflect.Value, value []byte) error {
 			return err
 		}
 	case reflect.Ptr:
+		if field.Type().Elem().Kind() == reflect.Struct {
+			return setFromJSON(field, value)
+		}
 		if field.Type() == reflect.TypeOf((*string)(nil)) {
 			if value == nil {
 				field.Set(reflect.New(field.Type().Elem()))
@@ -137,9 +136,20 @@ func setFieldValue(field reflect.Value, value []byte) error {
 				field.Set(reflect.New(field.Type().Elem()))
 				return nil
 			}
-			log.Printf("setting *[]string slice field: %v", value)
 			return setPointerSliceFromBytes(field, value)
 		}
+		switch field.Type().Elem().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64, reflect.Bool:
+			if len(value) == 0 {
+				field.Set(reflect.Zero(field.Type()))
+				return nil
+			}
+			valuePtr := reflect.New(field.Type().Elem())
+			if err := setFieldValue(valuePtr.Elem(), value); err != nil {
+				return err
+			}
+			field.Set(valuePtr)
+		}
 	default:
 		return fmt.Errorf("unsupported type: %v", field.Kind())
 	}
@@ -171,7 +181,6 @@ func setPointerSliceFromBytes(field reflect.Value, value []byte) error {
 	}
 
 	str := string(value)
-	log.Printf("str: %v", str)
 
 	// Handle empty/null cases
 	if str == "" || str == "null" {
@@ -207,16 +216,12 @@ func setPointerSliceFromBytes(field reflect.Value, value []byte) error {
 	slice := slicePtr.Elem()
 	slice.Set(reflect.MakeSlice(sliceType, len(parts), len(parts)))
 
-	log.Printf("parts: %v", parts)
-	log.Printf("slice: %v", slice)
-
 	for i, part := range parts {
 		part = strings.TrimSpace(part)
 		elem := slice.Index(i)
 
 		switch elemType.Kind() {
 		case reflect.String:
-			log.Printf("setting string slice field: %s", part)
 			elem.SetString(part)
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			if intVal, err := strconv.ParseInt(part, 10, 64); err == nil {
@@ -261,11 +266,12 @@ func setSliceFromBytes(field reflect.Value, value []byte) error {
 		field.SetBytes(value)
 		return nil
 	}
+	if elemType.Kind() == reflect.Struct {
+		return setFromJSON(field, value)
+	}
 
 	// Parse as comma-separated values for other slice types
 	str := string(value)
-	log.Printf("Parsing as comma-separated values for other slice types")
-	log.Printf("str: %v", str)
 	if str == "" {
 		field.Set(reflect.MakeSlice(field.Type(), 0, 0))
 		return nil
@@ -296,16 +302,12 @@ func setSliceFromBytes(field reflect.Value, value []byte) error {
 
 	slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
 
-	log.Printf("parts: %v", parts)
-	log.Printf("slice: %v", slice)
-
 	for i, part := range parts {
 		part = strings.TrimSpace(part)
 		elem := slice.Index(i)
 
 		switch elemType.Kind() {
 		case reflect.String:
-			log.Printf("setting string slice field: %s", part)
 			elem.SetString(part)
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			if intVal, err := strconv.ParseInt(part, 10, 64); err == nil {
@@ -340,23 +342,13 @@ func setSliceFromBytes(field reflect.Value, value []byte) error {
 	return nil
 }
 
-func detectContentType(data []byte) (string, error) {
-	if len(data) < 8 {
-		return "application/octet-stream", nil
-	}
-
-	// Check magic bytes for common image formats
-	switch {
-	case data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
-		return "image/jpeg", nil
-	case data[0] == 0x89 && data[1] == 0x50 && data[2] == 0x4E && data[3] == 0x47:
-		return "image/png", nil
-	case data[0] == 0x47 && data[1] == 0x49 && data[2] == 0x46:
-		return "image/gif", nil
-	case data[0] == 0x52 && data[1] == 0x49 && data[2] == 0x46 && data[3] == 0x46 &&
-		data[8] == 0x57 && data[9] == 0x45 && data[10] == 0x42 && data[11] == 0x50:
-		return "image/webp", nil
-	default:
-		return "", fmt.Errorf("unknown content type")
+// setFromJSON sets nested structures, such as a location or a list of
+// categories, from a JSON encoded part value
+func setFromJSON(field reflect.Value, value []byte) error {
+	decoded := reflect.New(field.Type())
+	if err := json.Unmarshal(value, decoded.Interface()); err != nil {
+		return fmt.Errorf("failed to decode json of %v: %w", field.Type(), err)
 	}
+	field.Set(decoded.Elem())
+	return nil
 }
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+const (
+	sectionWork        = "work"
+	sectionSkillsTools = "skillsTools"
+	sectionProjects    = "projects"
+)
+
+// portfolioSections are the sections of the portfolio, each queried concurrently
+var portfolioSections = []string{sectionWork, sectionSkillsTools, sectionProjects}
+
+// formats the portfolio can respond with
+var portfolioFormats = append(append([]string{}, responseFormats...), mediaTypeJSONResume)
+
+// getPortfolioHandler returns work, skillsTools and projects in one response.
+// Each section is queried concurrently and a failing section is reported in
+// the errors list instead of failing the whole request, which is then not
+// cached. The portfolio can also be requested in the JSON Resume format.
+func (s *Service) getPortfolioHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, err := negotiateFormat(request, portfolioFormats)
+	if err != nil {
+		return notAcceptableResponse(err), nil
+	}
+
+	portfolio, stored, firstExpiry := s.getPortfolio(ctx)
+
+	if len(portfolio.Errors) == len(portfolioSections) {
+		log.Printf("error in getting portfolio: all sections failed")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, nil
+	}
+
+	var portfolioBody []byte
+	if format == mediaTypeJSONResume {
+		portfolioBody, err = encodeResponse(format, portfolio.JSONResume())
+	} else {
+		portfolioBody, err = encodeResponse(format, portfolio)
+	}
+
+	if err != nil {
+		log.Printf("error in serializing portfolio: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	res := s.Cache.cacheableResponse(request, portfolioBody, format, contentETag(format, firstExpiry, stored), s.Cache.maxAgeUntil(firstExpiry))
+	if len(portfolio.Errors) > 0 {
+		return noStore(res), nil
+	}
+	return res, nil
+}
+
+// getPortfolio returns the portfolio, each of its sections as it is stored,
+// before presigned URLs are added, for contentETag, and when the first
+// presigned URL in it is regenerated
+func (s *Service) getPortfolio(ctx context.Context) (models.Portfolio, []json.RawMessage, time.Time) {
+	var portfolio models.Portfolio
+	var storedWork, storedSkillsTools, storedProjects json.RawMessage
+	var workExpiry, projectsExpiry time.Time
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	addError := func(section string, err error) {
+		log.Printf("error in getting %s for portfolio: %v", section, err)
+		mu.Lock()
+		defer mu.Unlock()
+		portfolio.Errors = append(portfolio.Errors, models.PortfolioError{
+			Section: section,
+			Message: "There was an error in getting " + section,
+		})
+	}
+
+	getSection := map[string]func() error{
+		sectionWork: func() error {
+			work, err := database.GetWork(ctx, s.DB.Client, s.TableName)
+			if err == nil {
+				storedWork, _ = json.Marshal(work)
+				workExpiry = s.presignCompanyLogos(ctx, work)
+			}
+			portfolio.Work = work
+			return err
+		},
+		sectionSkillsTools: func() error {
+			skillsTools, err := database.GetSkillsTools(ctx, s.DB.Client, s.TableName)
+			if err == nil {
+				storedSkillsTools, _ = json.Marshal(skillsTools)
+			}
+			portfolio.SkillsTools = skillsTools
+			return err
+		},
+		sectionProjects: func() error {
+			projects, err := database.GetProjects(ctx, s.DB.Client, s.TableName)
+			if err == nil {
+				storedProjects, _ = json.Marshal(projects)
+				projectsExpiry = s.presignProjectMediaLinks(ctx, projects)
+			}
+			portfolio.Projects = projects
+			return err
+		},
+	}
+
+	wg.Add(len(portfolioSections))
+	for _, section := range portfolioSections {
+		go func() {
+			defer wg.Done()
+			if err := getSection[section](); err != nil {
+				addError(section, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored := []json.RawMessage{storedWork, storedSkillsTools, storedProjects}
+	return portfolio, stored, earliest(workExpiry, projectsExpiry)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestGetPortfolioCaching(t *testing.T) {
+	for _, test := range []struct {
+		label              string
+		accept             string
+		failedSections     []string
+		expectedStatusCode int
+		expectedNoStore    bool
+	}{
+		{
+			label:              "Complete portfolio is cached",
+			accept:             mediaTypeJSON,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label:              "Partial portfolio is not cached",
+			accept:             mediaTypeJSON,
+			failedSections:     []string{"Work"},
+			expectedStatusCode: http.StatusOK,
+			expectedNoStore:    true,
+		},
+		{
+			label:              "Complete JSON Resume is cached",
+			accept:             mediaTypeJSONResume,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label:              "Partial JSON Resume is not cached",
+			accept:             mediaTypeJSONResume,
+			failedSections:     []string{"Projects"},
+			expectedStatusCode: http.StatusOK,
+			expectedNoStore:    true,
+		},
+		{
+			label:              "Every section failed",
+			accept:             mediaTypeJSONResume,
+			failedSections:     []string{"Work", "SkillsTools", "Projects"},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedProject(t, s, fake)
+			fake.failQuery = func(personalWebsiteType string) bool {
+				return slices.Contains(test.failedSections, personalWebsiteType)
+			}
+
+			res, _ := s.getPortfolioHandler(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Path:       "/api/v1/portfolio",
+				Headers:    map[string]string{"Accept": test.accept},
+			})
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected %d, got %d %s", test.expectedStatusCode, res.StatusCode, res.Body)
+			}
+			if res.StatusCode != http.StatusOK {
+				return
+			}
+			if cacheControl := res.Headers["Cache-Control"]; (cacheControl == "no-store") != test.expectedNoStore {
+				t.Errorf("expected no-store %v, got Cache-Control %q", test.expectedNoStore, cacheControl)
+			}
+			if contentType := res.Headers["Content-Type"]; contentType != test.accept {
+				t.Errorf("expected Content-Type %s, got %s", test.accept, contentType)
+			}
+		})
+	}
+}
@@ -3,20 +3,24 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
 	"github.com/thomasmendez/personal-website-backend/api/database"
 	"github.com/thomasmendez/personal-website-backend/api/models"
 )
 
 func (s *Service) getProjectsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, err := negotiateFormat(request, responseFormats)
+	if err != nil {
+		return notAcceptableResponse(err), nil
+	}
+
 	projects, err := database.GetProjects(ctx, s.DB.Client, s.TableName)
 
 	if err != nil {
@@ -27,25 +31,12 @@ func (s *Service) getProjectsHandler(ctx context.Context, request events.APIGate
 		}, err
 	}
 
-	// Generate presigned URL for mediaLink
-	for i, project := range projects {
-		if project.MediaLinkIsS3Bucket() {
-			if fileName, err := project.GetFileNameFromMediaLink(); fileName != "" {
-				presignedReq, err := s.S3.GeneratePresignedURL(ctx, fileName)
-				if err != nil {
-					log.Printf("error in generating presigned URL: %v", err)
-					log.Printf("skipping generation of presigned URL for project %s", project.SortValue)
-				} else {
-					projects[i].MediaLink = &presignedReq.URL
-				}
-			} else {
-				log.Printf("error in getting filename from mediaLink: %v", err)
-				log.Printf("skipping generation of presigned URL for project %s", project.SortValue)
-			}
-		}
-	}
+	// the ETag is of the projects as stored, see contentETag
+	stored, _ := json.Marshal(projects)
+	firstExpiry := s.presignProjectMediaLinks(ctx, projects)
+	etag := contentETag(format, firstExpiry, json.RawMessage(stored))
 
-	projectsJson, err := json.Marshal(projects)
+	projectsBody, err := encodeResponse(format, projects)
 
 	if err != nil {
 		log.Printf("error in serializing projects: %v", err)
@@ -55,84 +46,109 @@ func (s *Service) getProjectsHandler(ctx context.Context, request events.APIGate
 		}, err
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Body:       string(projectsJson),
-	}, err
+	return s.Cache.cacheableResponse(request, projectsBody, format, etag, s.Cache.maxAgeUntil(firstExpiry)), nil
 }
 
-func (s *Service) postProjectsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var newProject *models.Project
-	var imageFile models.FileData
-	var err error
+// presignProjectMediaLinks replaces S3 mediaLinks with presigned URLs and
+// returns when the first of them is regenerated, or the zero time if there
+// are none
+func (s *Service) presignProjectMediaLinks(ctx context.Context, projects []models.Project) (firstExpiry time.Time) {
+	for i := range projects {
+		firstExpiry = earliest(firstExpiry, s.presignProjectMedia(ctx, &projects[i]))
+	}
+	return firstExpiry
+}
 
-	log.Printf("POST project request: %v", request)
-	if request.IsBase64Encoded || strings.Contains(getContentType(request.Headers), "'multipart/form-data") {
-		fmt.Println("parsing form data")
-		newProject, imageFile, err = parseFormData[models.Project](request)
-		if err != nil {
-			log.Printf("error parsing form data: %v", err)
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusBadRequest,
-				Body:       resError(http.StatusBadRequest),
-			}, err
-		}
-	} else if !request.IsBase64Encoded && strings.Contains(getContentType(request.Headers), "application/json") {
-		fmt.Println("parsing json")
-		err = json.Unmarshal([]byte(request.Body), &newProject)
-		if err != nil {
-			log.Printf("error in deserializing json: %v", err)
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusBadRequest,
-				Body:       resError(http.StatusBadRequest),
-			}, err
-		}
-		if newProject.MediaLink != nil {
-			log.Printf("error: mediaLink has invalid content, please use multipart/form-data")
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusBadRequest,
-				Body:       resError(http.StatusBadRequest),
-			}, err
+// presignProjectMedia sets the mediaLink, gallery links and the links of their
+// variants and posters to the URLs they are served from, presigned URLs for
+// S3 objects, and returns when the first of them is regenerated
+func (s *Service) presignProjectMedia(ctx context.Context, project *models.Project) (firstExpiry time.Time) {
+	if ref := project.GetMediaRef(); ref != nil {
+		mediaLink, expires := s.resolveMediaLink(ctx, ref)
+		project.MediaLink = &mediaLink
+		firstExpiry = earliest(firstExpiry, expires)
+	}
+
+	firstExpiry = earliest(firstExpiry, s.presignVariants(ctx, project.MediaVariants, project.MediaPoster))
+
+	for i := range project.Media {
+		item := &project.Media[i]
+		firstExpiry = earliest(firstExpiry, s.presignVariants(ctx, item.Variants, item.Poster))
+		if ref := item.GetRef(); ref != nil {
+			var expires time.Time
+			item.MediaLink, expires = s.resolveMediaLink(ctx, ref)
+			firstExpiry = earliest(firstExpiry, expires)
 		}
-	} else {
-		log.Println("POST project request format is invalid")
-		log.Printf("isBase64Encoded: %v", request.IsBase64Encoded)
-		log.Printf("headers: %v", request.Headers)
+	}
+	return firstExpiry
+}
 
+func (s *Service) postProjectsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	log.Printf("POST project request: %v", request)
+	newProject, files, err := decodeRequestBody[models.Project](request, s.Uploads)
+	if err != nil {
+		log.Printf("error in decoding request body: %v", err)
+		return uploadErrorResponse(err), err
+	}
+	if !isFormData(request) && newProject.MediaLink != nil {
+		log.Printf("error: mediaLink has invalid content, please use multipart/form-data")
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusBadRequest,
 			Body:       resError(http.StatusBadRequest),
-		}, nil
+		}, err
 	}
 
+	// the gallery, variants and posters are only set from uploaded files
+	s.setMediaRef(newProject)
+	media := splitMediaFiles(files)
+	if err := checkPosters(append(media.Posters, media.Poster)); err != nil {
+		log.Printf("error in poster files: %v", err)
+		return uploadErrorResponse(err), err
+	}
+	newProject.Media = nil
+	newProject.MediaVariants = nil
+	newProject.MediaPoster = nil
+
 	// Upload image to S3 if it exists
-	var presignedReq *v4.PresignedHTTPRequest
-	if imageFile.Filename != "" && imageFile.Content != nil && imageFile.ContentType != "" {
-		log.Printf("uploading image file: %s to S3", imageFile.Filename)
-		mediaLink, err := s.S3.SendFileToS3(ctx, imageFile)
+	if imageFile := media.Cover; imageFile.Filename != "" && imageFile.Content != nil && imageFile.ContentType != "" {
+		key := projectMediaKey(*newProject, imageFile)
+		log.Printf("uploading image file: %s to S3 as %s", imageFile.Filename, key)
+		ref, variants, err := s.S3.UploadMedia(ctx, key, imageFile)
 		if err != nil {
-			fmt.Println("failed to upload to S3: %w", err)
+			log.Printf("failed to upload to S3: %v", err)
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusInternalServerError,
 				Body:       resError(http.StatusInternalServerError),
 			}, err
 		}
-		newProject.MediaLink = &mediaLink
-		// get presign url for response
-		presignedReq, err = s.S3.GeneratePresignedURL(ctx, imageFile.Filename)
-		if err != nil {
-			log.Printf("error in generating presigned URL: %v", err)
-			log.Printf("skipping generation of presigned URL for project %s", newProject.SortValue)
-		}
+		newProject.MediaRef = ref
+		newProject.MediaVariants = variants
 	} else {
 		log.Printf("no valid image file provided in request")
 	}
 
+	if media.Poster.Content != nil {
+		newProject.MediaPoster, err = s.uploadPoster(ctx, *newProject, media.Poster)
+	}
+	if err == nil {
+		err = s.uploadMediaItems(ctx, newProject, media.Gallery, media.Posters, models.ProjectMedia{})
+	}
+	if err != nil {
+		log.Printf("failed to upload media to S3: %v", err)
+		s.deleteUnreferencedMedia(ctx, newProject.MediaFileNames(), unsavedProject(*newProject))
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
 	log.Printf("adding new project: %v to database", newProject)
+	newProject.SetMediaRefs(s.S3.ParseMediaLink)
 	project, err := database.PostProject(ctx, s.DB.Client, s.TableName, *newProject)
 
 	if err != nil {
 		log.Printf("error in inserting project: %v", err)
+		s.deleteUnreferencedMedia(ctx, newProject.MediaFileNames(), unsavedProject(*newProject))
 		errRes := ErrorResponse{
 			Message: fmt.Sprintf("error in inserting project: %s", newProject.SortValue),
 		}
@@ -143,10 +159,8 @@ func (s *Service) postProjectsHandler(ctx context.Context, request events.APIGat
 		}, err
 	}
 
-	// add presigned url to project response if created
-	if presignedReq != nil {
-		project.MediaLink = &presignedReq.URL
-	}
+	// add presigned urls to project response
+	s.presignProjectMedia(ctx, &project)
 
 	projectJson, err := json.Marshal(project)
 
@@ -169,51 +183,55 @@ func (s *Service) postProjectsHandler(ctx context.Context, request events.APIGat
 }
 
 func (s *Service) updateProjectsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var updateProject *models.Project
-	var imageFile models.FileData
-	var err error
-
 	log.Printf("UPDATE project request: %v", request)
-	if request.IsBase64Encoded || strings.Contains(getContentType(request.Headers), "'multipart/form-data") {
-		log.Printf("parsing form data")
-		updateProject, imageFile, err = parseFormData[models.Project](request)
-		if err != nil {
-			log.Printf("error parsing form data: %v", err)
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusBadRequest,
-				Body:       resError(http.StatusBadRequest),
-			}, err
-		}
-	} else if !request.IsBase64Encoded && strings.Contains(getContentType(request.Headers), "application/json") {
-		log.Printf("parsing json")
-		err = json.Unmarshal([]byte(request.Body), &updateProject)
-		if err != nil {
-			log.Printf("error in deserializing json: %v", err)
+	updateProject, files, err := decodeRequestBody[models.Project](request, s.Uploads)
+	if err != nil {
+		log.Printf("error in decoding request body: %v", err)
+		return uploadErrorResponse(err), err
+	}
+	if !isFormData(request) && updateProject.MediaLink != nil && *updateProject.MediaLink != "" {
+		if !strings.HasPrefix(*updateProject.MediaLink, "http") {
+			log.Printf("error: mediaLink has invalid content, please use multipart/form-data")
 			return events.APIGatewayProxyResponse{
 				StatusCode: http.StatusBadRequest,
 				Body:       resError(http.StatusBadRequest),
-			}, err
+			}, nil
 		}
-		if updateProject.MediaLink != nil {
-			if !strings.HasPrefix(*updateProject.MediaLink, "http") {
-				log.Printf("error: mediaLink has invalid content, please use multipart/form-data")
-				return events.APIGatewayProxyResponse{
-					StatusCode: http.StatusBadRequest,
-					Body:       resError(http.StatusBadRequest),
-				}, nil
-			}
-		}
-	} else {
-		fmt.Println("PUT project request format is invalid")
+	}
+
+	var existingProject models.Project
+	err = database.GetItem(ctx, s.DB.Client, s.TableName, updateProject.PersonalWebsiteType, updateProject.SortValue, &existingProject)
+	if err != nil {
+		log.Printf("error in getting project: %v", err)
 		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       resError(http.StatusBadRequest),
-		}, nil
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+	existingFileNames := existingProject.MediaFileNames()
+
+	// the gallery is managed through the media endpoints and uploaded files.
+	// A request without a mediaLink keeps the cover, an empty one clears it.
+	keepCover := updateProject.MediaLink == nil
+	s.setMediaRef(updateProject)
+	media := splitMediaFiles(files)
+	if err := checkPosters(append(media.Posters, media.Poster)); err != nil {
+		log.Printf("error in poster files: %v", err)
+		return uploadErrorResponse(err), err
+	}
+	updateProject.Media = existingProject.Media
+	updateProject.MediaVariants = nil
+	updateProject.MediaPoster = nil
+	if keepCover || sameCover(*updateProject, existingProject) {
+		updateProject.MediaRef = existingProject.GetMediaRef()
+		updateProject.MediaVariants = existingProject.MediaVariants
+		updateProject.MediaPoster = existingProject.MediaPoster
 	}
 
-	if imageFile.Filename != "" && imageFile.Content != nil && imageFile.ContentType != "" {
-		log.Printf("uploading image file: %s to S3", imageFile.Filename)
-		mediaLink, err := s.S3.SendFileToS3(ctx, imageFile)
+	if imageFile := media.Cover; imageFile.Filename != "" && imageFile.Content != nil && imageFile.ContentType != "" {
+		key := projectMediaKey(*updateProject, imageFile)
+		log.Printf("uploading image file: %s to S3 as %s", imageFile.Filename, key)
+		ref, variants, err := s.S3.UploadMedia(ctx, key, imageFile)
 		if err != nil {
 			log.Printf("failed to upload to S3: %v", err)
 			return events.APIGatewayProxyResponse{
@@ -221,14 +239,36 @@ func (s *Service) updateProjectsHandler(ctx context.Context, request events.APIG
 				Body:       resError(http.StatusInternalServerError),
 			}, err
 		}
-		updateProject.MediaLink = &mediaLink
+		if !sameCover(models.Project{MediaRef: ref}, existingProject) {
+			updateProject.MediaPoster = nil
+		}
+		updateProject.MediaRef = ref
+		updateProject.MediaVariants = variants
+	}
+
+	if media.Poster.Content != nil {
+		updateProject.MediaPoster, err = s.uploadPoster(ctx, *updateProject, media.Poster)
+	}
+	if err == nil {
+		err = s.uploadMediaItems(ctx, updateProject, media.Gallery, media.Posters, models.ProjectMedia{})
+	}
+	if err != nil {
+		log.Printf("failed to upload media to S3: %v", err)
+		s.deleteUnreferencedMedia(ctx, updateProject.MediaFileNames(), existingProject)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
 	}
 
 	log.Printf("updating project: %v", updateProject)
+	updateProject.SetMediaRefs(s.S3.ParseMediaLink)
 	project, err := database.UpdateProject(ctx, s.DB.Client, s.TableName, *updateProject)
 
 	if err != nil {
 		log.Printf("error in updating project: %v", err)
+		// roll back the uploads, except objects the project already references
+		s.deleteUnreferencedMedia(ctx, updateProject.MediaFileNames(), existingProject)
 		errRes := ErrorResponse{
 			Message: fmt.Sprintf("error in updating project with sortValue of: %s", updateProject.SortValue),
 		}
@@ -239,6 +279,10 @@ func (s *Service) updateProjectsHandler(ctx context.Context, request events.APIG
 		}, err
 	}
 
+	// the previous media is only deleted once the project no longer references it
+	s.deleteUnreferencedMedia(ctx, existingFileNames, project)
+
+	s.presignProjectMedia(ctx, &project)
 	projectJson, err := json.Marshal(project)
 
 	if err != nil {
@@ -283,36 +327,12 @@ func (s *Service) deleteProjectHandler(ctx context.Context, request events.APIGa
 	}
 
 	log.Printf("existing project: %v", existingProject)
-	if existingProject.MediaLink != nil {
-		if existingProject.MediaLinkIsS3Bucket() {
-			if fileName, err := existingProject.GetFileNameFromMediaLink(); fileName != "" {
-				if exists, err := s.S3.FileExistsInS3(ctx, fileName); exists {
-					err = s.S3.DeleteFileFromS3(ctx, fileName)
-					if err != nil {
-						log.Printf("error in deleting file from S3: %v", err)
-						return events.APIGatewayProxyResponse{
-							StatusCode: http.StatusInternalServerError,
-							Body:       resError(http.StatusInternalServerError),
-						}, err
-					}
-				} else {
-					if err != nil {
-						var nf *types.NoSuchKey
-						if errors.As(err, &nf) {
-							log.Printf("file %s does not exist in S3", fileName)
-						}
-						log.Printf("error in getting file from S3: %v", err)
-					}
-				}
-			} else {
-				log.Printf("error in getting filename from mediaLink %s: %v", *existingProject.MediaLink, err)
-				log.Printf("skipping deletion of file from S3")
-			}
-		} else {
-			log.Printf("mediaLink for project %s is not a valid S3 bucket link", *existingProject.MediaLink)
-		}
-	} else {
-		log.Printf("mediaLink for project %s is nil", deleteProject.SortValue)
+	err = s.deleteProjectMedia(ctx, existingProject)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
 	}
 
 	log.Printf("deleting project: %v", deleteProject)
@@ -336,16 +356,64 @@ func (s *Service) deleteProjectHandler(ctx context.Context, request events.APIGa
 	}, err
 }
 
-func getContentType(headers map[string]string) string {
-	// Try different case variations
-	if ct := headers["Content-Type"]; ct != "" {
-		return ct
+// deleteProjectMedia deletes the S3 objects of the project's mediaLink and
+// media gallery. Links that are not S3 objects, objects outside the
+// project's prefix or objects that no longer exist are skipped.
+func (s *Service) deleteProjectMedia(ctx context.Context, project models.Project) error {
+	if ref := project.GetMediaRef(); ref != nil && !ref.IsS3() {
+		log.Printf("mediaLink for project %s is not a valid S3 bucket link", ref.URL)
+	}
+
+	for _, fileName := range project.MediaFileNames() {
+		if !isProjectMedia(project, fileName) {
+			log.Printf("not deleting media %s outside of project %s", fileName, project.SortValue)
+			continue
+		}
+		if err := s.deleteMediaFile(ctx, fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsavedProject returns the project identified by project that references
+// none of its media, whose uploads are rolled back when it is not saved
+func unsavedProject(project models.Project) models.Project {
+	return models.Project{PersonalWebsiteType: project.PersonalWebsiteType, SortValue: project.SortValue}
+}
+
+// sameCover reports whether project keeps the mediaLink of existingProject,
+// in which case its variants and poster are kept as well. S3 objects are
+// compared by key since responses contain presigned URLs.
+func sameCover(project models.Project, existingProject models.Project) bool {
+	ref, existingRef := project.GetMediaRef(), existingProject.GetMediaRef()
+	if ref == nil || existingRef == nil {
+		return false
 	}
-	if ct := headers["content-type"]; ct != "" { // this is dumb
-		return ct
+	if ref.IsS3() || existingRef.IsS3() {
+		return ref.IsS3() && existingRef.IsS3() && ref.Key == existingRef.Key
 	}
-	if ct := headers["Content-type"]; ct != "" { // also dumb
-		return ct
+	return ref.URL == existingRef.URL
+}
+
+// setMediaRef replaces the mediaLink of a request, a URL the API returned or
+// an external link, with its reference. An empty mediaLink has no reference.
+// References cannot be set directly.
+func (s *Service) setMediaRef(project *models.Project) {
+	project.MediaRef = nil
+	if project.MediaLink != nil {
+		project.MediaRef = s.S3.ParseMediaLink(*project.MediaLink)
+		project.MediaLink = nil
 	}
-	return ""
+}
+
+// projectMediaKey returns the content addressed S3 key of file under the
+// project's prefix, e.g. projects/personal-website-bff86b0df1a97b87/<sha256>.png
+func projectMediaKey(project models.Project, file models.FileData) string {
+	return bucket.MediaKey(projectMediaPrefix(project), file)
+}
+
+// projectMediaPrefix returns the S3 key prefix of the project's media
+func projectMediaPrefix(project models.Project) string {
+	return bucket.KeyPrefix(bucket.ProjectMediaPrefix, project.SortValue)
 }
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+const (
+	// form name of file parts that are added to the media gallery, any other
+	// file part sets the project's mediaLink
+	mediaFieldName = "media"
+	// form name of the poster image of the mediaLink
+	posterFieldName = "poster"
+	// form name of the poster images of gallery files, in the same order
+	postersFieldName = "posters"
+)
+
+// content types a poster image can have
+var posterContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/avif"}
+
+// mediaFiles are the file parts of a project request
+type mediaFiles struct {
+	Cover   models.FileData
+	Poster  models.FileData
+	Gallery []models.FileData
+	Posters []models.FileData
+}
+
+// addProjectMediaHandler uploads the files of a multipart request and appends
+// them to the project's media gallery
+func (s *Service) addProjectMediaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	log.Printf("POST project media request: %v", request)
+	mediaRequest, files, err := parseFormData[models.ProjectMedia](request, s.Uploads)
+	if err != nil {
+		log.Printf("error parsing form data: %v", err)
+		return uploadErrorResponse(err), err
+	}
+	if len(files) == 0 {
+		log.Printf("error: no media files provided")
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       resError(http.StatusBadRequest),
+		}, nil
+	}
+
+	existingProject, errRes, err := s.getExistingProject(ctx, mediaRequest.PersonalWebsiteType, mediaRequest.SortValue)
+	if errRes != nil {
+		return *errRes, err
+	}
+
+	// every file other than posters is added to the gallery
+	media := splitMediaFiles(files)
+	galleryFiles := slices.DeleteFunc(slices.Clone(files), isPosterFile)
+	if err := checkPosters(media.Posters); err != nil {
+		log.Printf("error in poster files: %v", err)
+		return uploadErrorResponse(err), err
+	}
+
+	project := existingProject
+	project.Media = slices.Clone(existingProject.Media)
+	err = s.uploadMediaItems(ctx, &project, galleryFiles, media.Posters, *mediaRequest)
+	if err != nil {
+		log.Printf("failed to upload media to S3: %v", err)
+		s.deleteUnreferencedMedia(ctx, project.MediaFileNames(), existingProject)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	return s.updateProjectMedia(ctx, existingProject, project)
+}
+
+// reorderProjectMediaHandler sets the order of the project's media gallery.
+// The order must list every gallery item ID exactly once.
+func (s *Service) reorderProjectMediaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var mediaRequest models.ProjectMedia
+	err := json.Unmarshal([]byte(request.Body), &mediaRequest)
+	if err != nil {
+		log.Printf("error in deserializing json: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       resError(http.StatusBadRequest),
+		}, err
+	}
+
+	existingProject, errRes, err := s.getExistingProject(ctx, mediaRequest.PersonalWebsiteType, mediaRequest.SortValue)
+	if errRes != nil {
+		return *errRes, err
+	}
+
+	if len(mediaRequest.Order) != len(existingProject.Media) {
+		return mediaErrorResponse(http.StatusBadRequest, "order must list every media item of project %s", existingProject.SortValue), nil
+	}
+	media := make([]models.MediaItem, 0, len(mediaRequest.Order))
+	for _, id := range mediaRequest.Order {
+		i := existingProject.MediaIndex(id)
+		if i == -1 || slices.ContainsFunc(media, func(item models.MediaItem) bool { return item.ID == id }) {
+			return mediaErrorResponse(http.StatusBadRequest, "media item %s is unknown or listed more than once", id), nil
+		}
+		media = append(media, existingProject.Media[i])
+	}
+
+	project := existingProject
+	project.Media = media
+	return s.updateProjectMedia(ctx, existingProject, project)
+}
+
+// removeProjectMediaHandler removes an item from the project's media gallery
+// and deletes its S3 object
+func (s *Service) removeProjectMediaHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var mediaRequest models.ProjectMedia
+	err := json.Unmarshal([]byte(request.Body), &mediaRequest)
+	if err != nil {
+		log.Printf("error in deserializing json: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       resError(http.StatusBadRequest),
+		}, err
+	}
+
+	existingProject, errRes, err := s.getExistingProject(ctx, mediaRequest.PersonalWebsiteType, mediaRequest.SortValue)
+	if errRes != nil {
+		return *errRes, err
+	}
+
+	i := existingProject.MediaIndex(mediaRequest.ID)
+	if i == -1 {
+		return mediaErrorResponse(http.StatusNotFound, "media item %s not found in project %s", mediaRequest.ID, existingProject.SortValue), nil
+	}
+
+	project := existingProject
+	project.Media = slices.Delete(slices.Clone(existingProject.Media), i, i+1)
+	return s.updateProjectMedia(ctx, existingProject, project)
+}
+
+// getExistingProject returns the project a media request refers to, or the
+// error response when it cannot be found
+func (s *Service) getExistingProject(ctx context.Context, personalWebsiteType string, sortValue string) (models.Project, *events.APIGatewayProxyResponse, error) {
+	var existingProject models.Project
+	err := database.GetItem(ctx, s.DB.Client, s.TableName, personalWebsiteType, sortValue, &existingProject)
+	if err != nil {
+		log.Printf("error in getting project: %v", err)
+		return existingProject, &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+	if existingProject.SortValue == "" {
+		log.Printf("project %s not found", sortValue)
+		return existingProject, &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       resError(http.StatusNotFound),
+		}, nil
+	}
+	return existingProject, nil, nil
+}
+
+// updateProjectMedia saves the media of project. Uploads are rolled back when
+// the update fails, and objects existingProject referenced are deleted once
+// the update succeeds.
+func (s *Service) updateProjectMedia(ctx context.Context, existingProject models.Project, project models.Project) (events.APIGatewayProxyResponse, error) {
+	log.Printf("updating media of project: %v", project.SortValue)
+	project.SetMediaRefs(s.S3.ParseMediaLink)
+	updatedProject, err := database.UpdateProject(ctx, s.DB.Client, s.TableName, project)
+	if err != nil {
+		log.Printf("error in updating project: %v", err)
+		s.deleteUnreferencedMedia(ctx, project.MediaFileNames(), existingProject)
+		return mediaErrorResponse(http.StatusInternalServerError, "error in updating media of project with sortValue of: %s", project.SortValue), err
+	}
+
+	s.deleteUnreferencedMedia(ctx, existingProject.MediaFileNames(), updatedProject)
+
+	s.presignProjectMedia(ctx, &updatedProject)
+	projectJson, err := json.Marshal(updatedProject)
+	if err != nil {
+		log.Printf("error in serializing project: %v", err)
+		return mediaErrorResponse(http.StatusInternalServerError, "error in project response for: %s", project.SortValue), err
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(projectJson),
+	}, nil
+}
+
+// splitMediaFiles returns the file for the project's mediaLink, the files for
+// the media gallery and their posters. The last file part outside the gallery
+// sets the mediaLink, as it did before galleries were supported.
+func splitMediaFiles(files []models.FileData) mediaFiles {
+	var media mediaFiles
+	for _, file := range files {
+		switch file.FieldName {
+		case mediaFieldName:
+			media.Gallery = append(media.Gallery, file)
+		case posterFieldName:
+			media.Poster = file
+		case postersFieldName:
+			media.Posters = append(media.Posters, file)
+		default:
+			media.Cover = file
+		}
+	}
+	return media
+}
+
+func isPosterFile(file models.FileData) bool {
+	return file.FieldName == posterFieldName || file.FieldName == postersFieldName
+}
+
+// checkPosters returns an error wrapping errUnsupportedMediaType if a poster
+// is not a raster image
+func checkPosters(posters []models.FileData) error {
+	for _, poster := range posters {
+		if poster.Content != nil && !slices.Contains(posterContentTypes, poster.ContentType) {
+			return fmt.Errorf("%w: poster %s must be one of %v", errUnsupportedMediaType, poster.Filename, posterContentTypes)
+		}
+	}
+	return nil
+}
+
+// uploadPoster uploads the poster image of a video or document with its
+// metadata stripped. Posters are keyed by their own content, so they work for
+// external mediaLinks as well.
+func (s *Service) uploadPoster(ctx context.Context, project models.Project, file models.FileData) (*models.MediaVariant, error) {
+	content, err := imaging.StripMetadata(file.Content, file.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip metadata of poster %s: %w", file.Filename, err)
+	}
+	key := projectMediaKey(project, file)
+	file.Content = content
+
+	log.Printf("uploading poster: %s to S3 as %s", file.Filename, key)
+	ref, err := s.S3.SendFileToS3(ctx, key, file)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := imaging.Dimensions(content)
+	return &models.MediaVariant{
+		Name:        posterFieldName,
+		Width:       width,
+		Height:      height,
+		ContentType: file.ContentType,
+		Ref:         ref,
+	}, nil
+}
+
+// uploadMediaItems uploads files and appends them to the project's media
+// gallery. posters and the captions, altTexts and posterTimes of metadata
+// apply to the files in the same order. Files already in the gallery are
+// skipped.
+func (s *Service) uploadMediaItems(ctx context.Context, project *models.Project, files []models.FileData, posters []models.FileData, metadata models.ProjectMedia) error {
+	for i, file := range files {
+		if file.Content == nil || file.ContentType == "" {
+			return fmt.Errorf("media file %s has no content", file.Filename)
+		}
+
+		key := projectMediaKey(*project, file)
+		id := bucket.MediaItemID(key)
+		if project.MediaIndex(id) != -1 {
+			log.Printf("media file %s is already in the gallery of project %s as %s", file.Filename, project.SortValue, id)
+			continue
+		}
+
+		log.Printf("uploading media file: %s to S3 as %s", file.Filename, key)
+		ref, variants, err := s.S3.UploadMedia(ctx, key, file)
+		if err != nil {
+			return err
+		}
+
+		item := models.MediaItem{
+			ID:          id,
+			Ref:         ref,
+			ContentType: file.ContentType,
+			Variants:    variants,
+		}
+		if i < len(metadata.Captions) && metadata.Captions[i] != "" {
+			item.Caption = &metadata.Captions[i]
+		}
+		if i < len(metadata.AltTexts) && metadata.AltTexts[i] != "" {
+			item.AltText = &metadata.AltTexts[i]
+		}
+		if i < len(metadata.PosterTimes) && metadata.PosterTimes[i] > 0 {
+			item.PosterTime = &metadata.PosterTimes[i]
+		}
+		if i < len(posters) {
+			if item.Poster, err = s.uploadPoster(ctx, *project, posters[i]); err != nil {
+				return err
+			}
+		}
+		project.Media = append(project.Media, item)
+	}
+	return nil
+}
+
+// presignVariants sets the mediaLinks of variants and the poster to presigned
+// URLs and returns when the first of them is regenerated
+func (s *Service) presignVariants(ctx context.Context, variants []models.MediaVariant, poster *models.MediaVariant) (firstExpiry time.Time) {
+	for i := range variants {
+		firstExpiry = earliest(firstExpiry, s.presignVariant(ctx, &variants[i]))
+	}
+	if poster != nil {
+		firstExpiry = earliest(firstExpiry, s.presignVariant(ctx, poster))
+	}
+	return firstExpiry
+}
+
+func (s *Service) presignVariant(ctx context.Context, variant *models.MediaVariant) (expires time.Time) {
+	if ref := variant.GetRef(); ref != nil {
+		variant.MediaLink, expires = s.resolveMediaLink(ctx, ref)
+	}
+	return expires
+}
+
+// resolveMediaLink returns the URL the media of ref is served from and when
+// it is regenerated. S3 objects get presigned URLs, or their unsigned URL if
+// signing fails, and external links never expire.
+func (s *Service) resolveMediaLink(ctx context.Context, ref *models.MediaRef) (string, time.Time) {
+	if !ref.IsS3() {
+		return ref.URL, time.Time{}
+	}
+	presignedURL, expires, err := s.S3.GetPresignedURL(ctx, ref.Key)
+	if err != nil {
+		log.Printf("error in generating presigned URL for %s: %v", ref.Key, err)
+		return s.S3.ObjectURL(ref.Key), time.Time{}
+	}
+	return presignedURL, expires
+}
+
+// deleteUnreferencedMedia deletes the S3 objects in fileNames that project
+// does not reference. Objects outside the project's prefix are never deleted,
+// since mediaLinks set by clients may refer to media of other items. Errors
+// are logged since the project is already saved.
+func (s *Service) deleteUnreferencedMedia(ctx context.Context, fileNames []string, project models.Project) {
+	referenced := project.MediaFileNames()
+	for _, fileName := range fileNames {
+		if slices.Contains(referenced, fileName) {
+			continue
+		}
+		if !isProjectMedia(project, fileName) {
+			log.Printf("not deleting media %s outside of project %s", fileName, project.SortValue)
+			continue
+		}
+		log.Printf("deleting unreferenced media %s of project %s", fileName, project.SortValue)
+		if err := s.deleteMediaFile(ctx, fileName); err != nil {
+			log.Printf("error in deleting unreferenced media: %v", err)
+		}
+	}
+}
+
+// isProjectMedia reports whether the S3 object fileName is under the prefix
+// of the project's media
+func isProjectMedia(project models.Project, fileName string) bool {
+	return strings.HasPrefix(fileName, projectMediaPrefix(project)+"/")
+}
+
+// deleteMediaFile deletes the S3 object fileName if it exists
+func (s *Service) deleteMediaFile(ctx context.Context, fileName string) error {
+	if exists, err := s.S3.FileExistsInS3(ctx, fileName); exists {
+		err = s.S3.DeleteFileFromS3(ctx, fileName)
+		if err != nil {
+			log.Printf("error in deleting file from S3: %v", err)
+			return err
+		}
+	} else {
+		if err != nil {
+			var nf *types.NoSuchKey
+			if errors.As(err, &nf) {
+				log.Printf("file %s does not exist in S3", fileName)
+			}
+			log.Printf("error in getting file from S3: %v", err)
+		}
+	}
+	return nil
+}
+
+func mediaErrorResponse(statusCode int, format string, args ...interface{}) events.APIGatewayProxyResponse {
+	res, _ := json.Marshal(ErrorResponse{
+		Message: fmt.Sprintf(format, args...),
+	})
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(res),
+	}
+}
+
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
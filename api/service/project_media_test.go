@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"image/color"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// keys of the gallery items seedGallery stores
+var galleryKeys = []string{
+	"projects/personal-website-bff86b0df1a97b87/aaaaaaaaaaaaaaaa.png",
+	"projects/personal-website-bff86b0df1a97b87/bbbbbbbbbbbbbbbb.png",
+}
+
+// seedGallery stores a project whose cover is existingCoverKey and whose
+// gallery holds the objects of galleryKeys
+func seedGallery(t *testing.T, s *Service, fake *fakeAWS) models.Project {
+	t.Helper()
+	project := models.Project{
+		PersonalWebsiteType: "Projects",
+		SortValue:           "Personal Website",
+		Name:                "Personal Website",
+		MediaRef:            s.S3.MediaRef(existingCoverKey, "image/png", 4),
+	}
+	fake.objects[existingCoverKey] = fakeS3Object{content: []byte("png!"), contentType: "image/png"}
+	for _, key := range galleryKeys {
+		project.Media = append(project.Media, models.MediaItem{
+			ID:          mediaItemID(key),
+			Ref:         s.S3.MediaRef(key, "image/png", 4),
+			ContentType: "image/png",
+		})
+		fake.objects[key] = fakeS3Object{content: []byte("png!"), contentType: "image/png"}
+	}
+	if _, err := database.PostProject(context.Background(), s.DB.Client, s.TableName, project); err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func mediaItemID(key string) string {
+	return strings.TrimSuffix(path.Base(key), ".png")
+}
+
+func galleryIDs(project models.Project) []string {
+	ids := make([]string, 0, len(project.Media))
+	for _, item := range project.Media {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func objectKeys(fake *fakeAWS) []string {
+	keys := make([]string, 0, len(fake.objects))
+	for key := range fake.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestAddProjectMedia(t *testing.T) {
+	first := models.FileData{FieldName: mediaFieldName, Filename: "first.png", Content: testPNG(t, 8, 8, color.White)}
+	second := models.FileData{FieldName: mediaFieldName, Filename: "second.png", Content: testPNG(t, 8, 8, color.Black)}
+	second.ContentType = "image/png"
+	secondKey := projectMediaKey(models.Project{SortValue: "Personal Website"}, second)
+
+	for _, test := range []struct {
+		label      string
+		sortValue  string
+		failPut    func(key string) bool
+		failUpdate bool
+		// expectedAdded is the number of items added to the gallery
+		expectedAdded      int
+		expectedStatusCode int
+	}{
+		{
+			label:              "Adds the files to the gallery",
+			sortValue:          "Personal Website",
+			expectedAdded:      2,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label:              "Unknown project",
+			sortValue:          "Unknown",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			label:              "Failed upload rolls back the other uploads",
+			sortValue:          "Personal Website",
+			failPut:            func(key string) bool { return key == secondKey },
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			label:              "Failed update rolls back the uploads",
+			sortValue:          "Personal Website",
+			failUpdate:         true,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seeded := seedGallery(t, s, fake)
+			seededKeys := objectKeys(fake)
+			fake.failPut = test.failPut
+			fake.failUpdate = test.failUpdate
+
+			res, _ := s.addProjectMediaHandler(context.Background(), multipartRequest(t, [][2]string{
+				{"personalWebsiteType", "Projects"},
+				{"sortValue", test.sortValue},
+				{"captions", "First"},
+			}, first, second))
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected %d, got %d %s", test.expectedStatusCode, res.StatusCode, res.Body)
+			}
+
+			project := getProject(t, s, "Personal Website")
+			if len(project.Media) != len(seeded.Media)+test.expectedAdded {
+				t.Fatalf("expected %d gallery items, got %v", len(seeded.Media)+test.expectedAdded, galleryIDs(project))
+			}
+			if test.expectedAdded == 0 {
+				if keys := objectKeys(fake); !reflect.DeepEqual(keys, seededKeys) {
+					t.Errorf("expected the uploads to be rolled back to %v, got %v", seededKeys, keys)
+				}
+				return
+			}
+			for _, item := range project.Media[len(seeded.Media):] {
+				if _, ok := fake.objects[item.GetRef().Key]; !ok {
+					t.Errorf("expected %s to be uploaded", item.GetRef().Key)
+				}
+			}
+			if added := project.Media[len(seeded.Media)]; added.Caption == nil || *added.Caption != "First" {
+				t.Errorf("expected the caption of the first file, got %v", added.Caption)
+			}
+		})
+	}
+}
+
+func TestReorderProjectMedia(t *testing.T) {
+	a, b := mediaItemID(galleryKeys[0]), mediaItemID(galleryKeys[1])
+	for _, test := range []struct {
+		label              string
+		order              []string
+		expectedStatusCode int
+		expectedOrder      []string
+	}{
+		{
+			label:              "Reorders the gallery",
+			order:              []string{b, a},
+			expectedStatusCode: http.StatusOK,
+			expectedOrder:      []string{b, a},
+		},
+		{
+			label:              "Missing item",
+			order:              []string{b},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOrder:      []string{a, b},
+		},
+		{
+			label:              "Duplicate item",
+			order:              []string{b, b},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOrder:      []string{a, b},
+		},
+		{
+			label:              "Unknown item",
+			order:              []string{b, "unknown"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedOrder:      []string{a, b},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedGallery(t, s, fake)
+			seededKeys := objectKeys(fake)
+
+			res, _ := s.reorderProjectMediaHandler(context.Background(), jsonRequest(t, http.MethodPatch, "/api/v1/projects/media", models.ProjectMedia{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				Order:               test.order,
+			}))
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected %d, got %d %s", test.expectedStatusCode, res.StatusCode, res.Body)
+			}
+
+			if ids := galleryIDs(getProject(t, s, "Personal Website")); !reflect.DeepEqual(ids, test.expectedOrder) {
+				t.Errorf("expected order %v, got %v", test.expectedOrder, ids)
+			}
+			if keys := objectKeys(fake); !reflect.DeepEqual(keys, seededKeys) {
+				t.Errorf("expected the objects %v to be kept, got %v", seededKeys, keys)
+			}
+		})
+	}
+}
+
+func TestRemoveProjectMedia(t *testing.T) {
+	a, b := mediaItemID(galleryKeys[0]), mediaItemID(galleryKeys[1])
+	for _, test := range []struct {
+		label              string
+		request            events.APIGatewayProxyRequest
+		failUpdate         bool
+		expectedStatusCode int
+		expectedGallery    []string
+		expectedDeleted    string
+	}{
+		{
+			label: "Removes the item and deletes its object",
+			request: jsonRequest(t, http.MethodDelete, "/api/v1/projects/media", models.ProjectMedia{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				ID:                  a,
+			}),
+			expectedStatusCode: http.StatusOK,
+			expectedGallery:    []string{b},
+			expectedDeleted:    galleryKeys[0],
+		},
+		{
+			label: "Unknown item",
+			request: jsonRequest(t, http.MethodDelete, "/api/v1/projects/media", models.ProjectMedia{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				ID:                  "unknown",
+			}),
+			expectedStatusCode: http.StatusNotFound,
+			expectedGallery:    []string{a, b},
+		},
+		{
+			label: "Failed update keeps the object",
+			request: jsonRequest(t, http.MethodDelete, "/api/v1/projects/media", models.ProjectMedia{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				ID:                  a,
+			}),
+			failUpdate:         true,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedGallery:    []string{a, b},
+		},
+		{
+			label:              "Invalid json",
+			request:            events.APIGatewayProxyRequest{HTTPMethod: http.MethodDelete, Body: "{"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedGallery:    []string{a, b},
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedGallery(t, s, fake)
+			fake.failUpdate = test.failUpdate
+
+			res, _ := s.removeProjectMediaHandler(context.Background(), test.request)
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected %d, got %d %s", test.expectedStatusCode, res.StatusCode, res.Body)
+			}
+
+			if ids := galleryIDs(getProject(t, s, "Personal Website")); !reflect.DeepEqual(ids, test.expectedGallery) {
+				t.Errorf("expected gallery %v, got %v", test.expectedGallery, ids)
+			}
+			for _, key := range append([]string{existingCoverKey}, galleryKeys...) {
+				if _, ok := fake.objects[key]; ok == (key == test.expectedDeleted) {
+					t.Errorf("expected %s to be deleted %v", key, key == test.expectedDeleted)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadPoster(t *testing.T) {
+	s, fake := newTestService(t)
+	project := models.Project{PersonalWebsiteType: "Projects", SortValue: "Personal Website"}
+
+	poster, err := s.uploadPoster(context.Background(), project, models.FileData{
+		FieldName:   posterFieldName,
+		Filename:    "poster.jpg",
+		Content:     testJPEG(t, 8, 4, color.White, 6),
+		ContentType: "image/jpeg",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, ok := fake.objects[poster.GetRef().Key]
+	if !ok {
+		t.Fatalf("expected %s to be uploaded", poster.GetRef().Key)
+	}
+	if orientation := imaging.JPEGOrientation(object.content); orientation != 6 {
+		t.Errorf("expected the poster to keep orientation 6, got %d", orientation)
+	}
+}
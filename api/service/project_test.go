@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+const existingCoverKey = "projects/personal-website-bff86b0df1a97b87/existing.png"
+
+// testPNG returns a PNG of width by height pixels in c
+func testPNG(t *testing.T, width int, height int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testJPEG returns a JPEG of width by height pixels in c with an EXIF
+// segment whose only tag is orientation
+func testJPEG(t *testing.T, width int, height int, c color.Color, orientation uint16) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	exif := []byte("Exif\x00\x00MM\x00*\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	exif = append(binary.BigEndian.AppendUint16(exif, orientation), 0, 0, 0, 0, 0, 0)
+	segment := append(binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(exif)+2)), exif...)
+	return append(append(append([]byte{}, buf.Bytes()[:2]...), segment...), buf.Bytes()[2:]...)
+}
+
+// seedProject stores a project whose cover is existingCoverKey
+func seedProject(t *testing.T, s *Service, fake *fakeAWS) models.Project {
+	t.Helper()
+	project := models.Project{
+		PersonalWebsiteType: "Projects",
+		SortValue:           "Personal Website",
+		Name:                "Personal Website",
+		MediaRef:            s.S3.MediaRef(existingCoverKey, "image/png", 4),
+	}
+	fake.objects[existingCoverKey] = fakeS3Object{content: []byte("png!"), contentType: "image/png"}
+	if _, err := database.PostProject(context.Background(), s.DB.Client, s.TableName, project); err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func getProject(t *testing.T, s *Service, sortValue string) models.Project {
+	t.Helper()
+	var project models.Project
+	if err := database.GetItem(context.Background(), s.DB.Client, s.TableName, "Projects", sortValue, &project); err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func jsonRequest(t *testing.T, method string, path string, body any) events.APIGatewayProxyRequest {
+	t.Helper()
+	content, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: method,
+		Path:       path,
+		Headers:    map[string]string{"Content-Type": mediaTypeJSON},
+		Body:       string(content),
+	}
+}
+
+func TestUpdateProjectCover(t *testing.T) {
+	for _, test := range []struct {
+		label string
+		// request returns the update of the seeded project
+		request            func(t *testing.T) events.APIGatewayProxyRequest
+		expectedKept       bool
+		expectedCover      bool
+		expectedName       string
+		expectedObjectKept bool
+	}{
+		{
+			label: "No mediaLink keeps the cover",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return jsonRequest(t, http.MethodPut, "/api/v1/projects", map[string]any{
+					"personalWebsiteType": "Projects",
+					"sortValue":           "Personal Website",
+					"name":                "Renamed",
+				})
+			},
+			expectedKept:       true,
+			expectedCover:      true,
+			expectedName:       "Renamed",
+			expectedObjectKept: true,
+		},
+		{
+			label: "Null mediaLink keeps the cover",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return jsonRequest(t, http.MethodPut, "/api/v1/projects", map[string]any{
+					"personalWebsiteType": "Projects",
+					"sortValue":           "Personal Website",
+					"name":                "Renamed",
+					"mediaLink":           nil,
+				})
+			},
+			expectedKept:       true,
+			expectedCover:      true,
+			expectedName:       "Renamed",
+			expectedObjectKept: true,
+		},
+		{
+			label: "Form without mediaLink keeps the cover",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return multipartRequest(t, [][2]string{
+					{"personalWebsiteType", "Projects"},
+					{"sortValue", "Personal Website"},
+					{"name", "Renamed"},
+				})
+			},
+			expectedKept:       true,
+			expectedCover:      true,
+			expectedName:       "Renamed",
+			expectedObjectKept: true,
+		},
+		{
+			label: "Empty mediaLink clears the cover",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return jsonRequest(t, http.MethodPut, "/api/v1/projects", map[string]any{
+					"personalWebsiteType": "Projects",
+					"sortValue":           "Personal Website",
+					"name":                "Personal Website",
+					"mediaLink":           "",
+				})
+			},
+			expectedName: "Personal Website",
+		},
+		{
+			label: "Uploaded file replaces the cover",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return multipartRequest(t, [][2]string{
+					{"personalWebsiteType", "Projects"},
+					{"sortValue", "Personal Website"},
+					{"name", "Personal Website"},
+				}, models.FileData{FieldName: "file", Filename: "cover.png", Content: testPNG(t, 8, 8, color.White)})
+			},
+			expectedCover: true,
+			expectedName:  "Personal Website",
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedProject(t, s, fake)
+
+			res, err := s.updateProjectsHandler(context.Background(), test.request(t))
+			if err != nil || res.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d %s: %v", res.StatusCode, res.Body, err)
+			}
+
+			project := getProject(t, s, "Personal Website")
+			if project.Name != test.expectedName {
+				t.Errorf("expected name %q, got %q", test.expectedName, project.Name)
+			}
+			ref := project.GetMediaRef()
+			if (ref != nil) != test.expectedCover {
+				t.Fatalf("expected cover %v, got %+v", test.expectedCover, ref)
+			}
+			if ref != nil && (ref.Key == existingCoverKey) != test.expectedKept {
+				t.Errorf("expected the existing cover to be kept %v, got %s", test.expectedKept, ref.Key)
+			}
+			if ref != nil && !test.expectedKept {
+				if _, ok := fake.objects[ref.Key]; !ok {
+					t.Errorf("expected the new cover %s to be uploaded", ref.Key)
+				}
+			}
+			if _, ok := fake.objects[existingCoverKey]; ok != test.expectedObjectKept {
+				t.Errorf("expected the existing cover object to be kept %v, got %v", test.expectedObjectKept, ok)
+			}
+		})
+	}
+}
+
+func TestProjectMediaOfOtherProjectsIsKept(t *testing.T) {
+	for _, test := range []struct {
+		label   string
+		handler func(*Service, context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		request events.APIGatewayProxyRequest
+	}{
+		{
+			label:   "Update clearing the cover",
+			handler: (*Service).updateProjectsHandler,
+			request: jsonRequest(t, http.MethodPut, "/api/v1/projects", map[string]any{
+				"personalWebsiteType": "Projects",
+				"sortValue":           "Other",
+				"name":                "Other",
+				"mediaLink":           "",
+			}),
+		},
+		{
+			label:   "Delete",
+			handler: (*Service).deleteProjectHandler,
+			request: jsonRequest(t, http.MethodDelete, "/api/v1/projects", map[string]any{
+				"personalWebsiteType": "Projects",
+				"sortValue":           "Other",
+			}),
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedProject(t, s, fake)
+			// the cover of the other project was set to a URL of the seeded one
+			other := models.Project{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Other",
+				Name:                "Other",
+				MediaRef:            s.S3.MediaRef(existingCoverKey, "image/png", 4),
+			}
+			if _, err := database.PostProject(context.Background(), s.DB.Client, s.TableName, other); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := test.handler(s, context.Background(), test.request)
+			if err != nil || res.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d %s: %v", res.StatusCode, res.Body, err)
+			}
+			if _, ok := fake.objects[existingCoverKey]; !ok {
+				t.Errorf("expected the cover of the seeded project to be kept")
+			}
+		})
+	}
+}
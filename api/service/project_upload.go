@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// getUploadURLHandler returns a presigned URL for uploading a project's media
+// directly to S3. The key is content addressed by the checksum, which S3
+// verifies on upload, so the object can be attached with
+// confirmUploadHandler once the upload succeeds.
+func (s *Service) getUploadURLHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var uploadRequest models.UploadRequest
+	err := json.Unmarshal([]byte(request.Body), &uploadRequest)
+	if err != nil {
+		log.Printf("error in deserializing json: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       resError(http.StatusBadRequest),
+		}, err
+	}
+
+	if err := s.Uploads.checkFile(uploadRequest.ContentType, uploadRequest.ContentLength, s.Uploads.maxUploadSize(uploadRequest.ContentType)); err != nil {
+		log.Printf("error in upload request: %v", err)
+		return uploadErrorResponse(err), nil
+	}
+	if uploadRequest.ContentLength <= 0 {
+		return mediaErrorResponse(http.StatusBadRequest, "contentLength must be the size of the file in bytes"), nil
+	}
+	checksum, err := hex.DecodeString(uploadRequest.ChecksumSHA256)
+	if err != nil || len(checksum) != 32 {
+		return mediaErrorResponse(http.StatusBadRequest, "checksumSha256 must be the hex encoded SHA-256 of the file"), nil
+	}
+
+	existingProject, errRes, err := s.getExistingProject(ctx, uploadRequest.PersonalWebsiteType, uploadRequest.SortValue)
+	if errRes != nil {
+		return *errRes, err
+	}
+
+	file := models.FileData{
+		Filename:    uploadRequest.Filename,
+		ContentType: uploadRequest.ContentType,
+	}
+	key := bucket.ChecksumMediaKey(projectMediaPrefix(existingProject), uploadRequest.ChecksumSHA256, file)
+	exists, err := s.S3.FileExistsInS3(ctx, key)
+	if err != nil {
+		log.Printf("error in getting file from S3: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	presignedReq, expires, err := s.S3.PresignUpload(ctx, key, file, uploadRequest.ContentLength, base64.StdEncoding.EncodeToString(checksum))
+	if err != nil {
+		log.Printf("error in generating upload URL: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	headers := make(map[string]string)
+	for name, values := range presignedReq.SignedHeader {
+		// the host header is set by the client from the URL
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		headers[name] = strings.Join(values, ",")
+	}
+
+	uploadJson, err := json.Marshal(models.UploadURL{
+		Key:       key,
+		UploadURL: presignedReq.URL,
+		Method:    presignedReq.Method,
+		Headers:   headers,
+		ExpiresAt: expires,
+		Exists:    exists,
+	})
+	if err != nil {
+		log.Printf("error in serializing upload URL: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Cache-Control": "no-store",
+		},
+		Body: string(uploadJson),
+	}, nil
+}
+
+// confirmUploadHandler sets the project's mediaLink to an object uploaded
+// with a presigned URL, after checking that it exists under the project's
+// prefix and that its content is the supported type it was uploaded as and
+// within the size limit. Images and SVGs are processed as multipart uploads
+// are, and the object is deleted if it cannot be used. The previous mediaLink
+// is deleted once the project is updated.
+func (s *Service) confirmUploadHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var confirmation models.UploadConfirmation
+	err := json.Unmarshal([]byte(request.Body), &confirmation)
+	if err != nil {
+		log.Printf("error in deserializing json: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       resError(http.StatusBadRequest),
+		}, err
+	}
+
+	existingProject, errRes, err := s.getExistingProject(ctx, confirmation.PersonalWebsiteType, confirmation.SortValue)
+	if errRes != nil {
+		return *errRes, err
+	}
+
+	if !isProjectMedia(existingProject, confirmation.Key) {
+		return mediaErrorResponse(http.StatusBadRequest, "key %s is not media of project %s", confirmation.Key, existingProject.SortValue), nil
+	}
+
+	info, err := s.S3.GetFileInfo(ctx, confirmation.Key)
+	if err != nil {
+		log.Printf("error in getting file from S3: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+	if info == nil {
+		return mediaErrorResponse(http.StatusNotFound, "no file has been uploaded to %s", confirmation.Key), nil
+	}
+
+	file, err := s.getUploadedFile(ctx, confirmation.Key, info)
+	if errors.Is(err, errUnsupportedMediaType) || errors.Is(err, errFileTooLarge) {
+		log.Printf("uploaded file %s is not valid media: %v", confirmation.Key, err)
+		s.deleteUnreferencedMedia(ctx, []string{confirmation.Key}, existingProject)
+		return uploadErrorResponse(err), nil
+	}
+	if err != nil {
+		log.Printf("error in getting uploaded file: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	ref := s.S3.MediaRef(confirmation.Key, file.ContentType, aws.ToInt64(info.ContentLength))
+	var variants []models.MediaVariant
+	if file.Content != nil {
+		log.Printf("processing uploaded file: %s", confirmation.Key)
+		ref, variants, err = s.S3.ReplaceMedia(ctx, confirmation.Key, file)
+		if err != nil {
+			log.Printf("failed to process uploaded file: %v", err)
+			s.deleteUnreferencedMedia(ctx, []string{confirmation.Key}, existingProject)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       resError(http.StatusInternalServerError),
+			}, err
+		}
+	}
+
+	project := existingProject
+	project.MediaLink = nil
+	project.MediaRef = ref
+	project.MediaVariants = variants
+	if !sameCover(project, existingProject) {
+		project.MediaPoster = nil
+	}
+	return s.updateProjectMedia(ctx, existingProject, project)
+}
+
+// getUploadedFile checks the object at key against the upload limits and
+// identifies its content, which must be the content type it was uploaded
+// as. Images and SVGs are returned with their content so they can be
+// processed, other files only with their content type. Errors for files
+// that cannot be used wrap errUnsupportedMediaType or errFileTooLarge.
+func (s *Service) getUploadedFile(ctx context.Context, key string, info *s3.HeadObjectOutput) (models.FileData, error) {
+	file := models.FileData{
+		Filename:    uploadedFilename(key, info),
+		ContentType: aws.ToString(info.ContentType),
+	}
+	if err := s.Uploads.checkFile(file.ContentType, aws.ToInt64(info.ContentLength), s.Uploads.maxUploadSize(file.ContentType)); err != nil {
+		return file, err
+	}
+
+	// images and SVGs are processed, so they are read in full
+	if !imaging.IsProcessable(file.ContentType) && file.ContentType != "image/svg+xml" {
+		content, err := s.S3.GetFileHead(ctx, key, mediatype.SniffLength)
+		if err != nil {
+			return file, err
+		}
+		contentType, err := mediatype.Detect(content)
+		if err != nil {
+			return file, fmt.Errorf("file %s: %w", key, err)
+		}
+		return file, checkUploadedType(key, file.ContentType, contentType)
+	}
+
+	content, err := s.S3.GetFile(ctx, key)
+	if err != nil {
+		return file, err
+	}
+	contentType, content, err := mediatype.Prepare(content, s.Uploads.AllowedContentTypes)
+	if err != nil {
+		return file, fmt.Errorf("file %s: %w", key, err)
+	}
+	file.Content = content
+	return file, checkUploadedType(key, file.ContentType, contentType)
+}
+
+// checkUploadedType returns an error wrapping errUnsupportedMediaType if the
+// detected content type of key is not the one it was uploaded as
+func checkUploadedType(key string, uploaded string, detected string) error {
+	if detected != uploaded {
+		return fmt.Errorf("%w: %s was uploaded as %s but is %s", errUnsupportedMediaType, key, uploaded, detected)
+	}
+	return nil
+}
+
+// uploadedFilename returns the filename of the Content-Disposition the object
+// was uploaded with, or the base of its key
+func uploadedFilename(key string, info *s3.HeadObjectOutput) string {
+	_, params, err := mime.ParseMediaType(aws.ToString(info.ContentDisposition))
+	if err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+	return path.Base(key)
+}
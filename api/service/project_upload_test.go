@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"net/http"
+	"testing"
+
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func TestConfirmUpload(t *testing.T) {
+	const uploadKey = "projects/personal-website-bff86b0df1a97b87/0123456789abcdef.bin"
+	png := testPNG(t, 400, 10, color.White)
+	pdf := []byte("%PDF-1.7\n%âãÏÓ\n")
+
+	for _, test := range []struct {
+		label               string
+		key                 string
+		object              *fakeS3Object
+		allowedContentTypes []string
+		maxImageSize        int64
+		expectedStatusCode  int
+		// expectedCover is whether the upload replaces the cover, otherwise it
+		// is deleted unless it was never uploaded
+		expectedCover    bool
+		expectedVariants bool
+		// expectedContent checks the stored object of a confirmed upload
+		expectedContent func(t *testing.T, content []byte)
+	}{
+		{
+			label:              "Image is processed",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: png, contentType: "image/png", disposition: `inline; filename="cover.png"`},
+			expectedStatusCode: http.StatusOK,
+			expectedCover:      true,
+			expectedVariants:   true,
+		},
+		{
+			label:              "Video and documents are stored as uploaded",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: pdf, contentType: "application/pdf"},
+			expectedStatusCode: http.StatusOK,
+			expectedCover:      true,
+			expectedContent: func(t *testing.T, content []byte) {
+				if !bytes.Equal(content, pdf) {
+					t.Errorf("expected the pdf to be stored as uploaded, got %q", content)
+				}
+			},
+		},
+		{
+			label:              "SVG is sanitized",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><rect/></svg>`), contentType: "image/svg+xml"},
+			expectedStatusCode: http.StatusOK,
+			expectedCover:      true,
+			expectedContent: func(t *testing.T, content []byte) {
+				if bytes.Contains(content, []byte("script")) || !bytes.Contains(content, []byte("<rect")) {
+					t.Errorf("expected the svg to be sanitized, got %s", content)
+				}
+			},
+		},
+		{
+			label:              "Invalid SVG",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: []byte(`<svg><g></svg>`), contentType: "image/svg+xml"},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:              "Content is not the declared type",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: png, contentType: "image/jpeg"},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:              "Content is not detectable",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: []byte("plain text"), contentType: "application/pdf"},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:               "Content type is not allowed",
+			key:                 uploadKey,
+			object:              &fakeS3Object{content: pdf, contentType: "application/pdf"},
+			allowedContentTypes: []string{"image/png"},
+			expectedStatusCode:  http.StatusUnsupportedMediaType,
+		},
+		{
+			label:              "Image over MaxImageSize",
+			key:                uploadKey,
+			object:             &fakeS3Object{content: png, contentType: "image/png"},
+			maxImageSize:       int64(len(png)) - 1,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			label:              "Not uploaded",
+			key:                uploadKey,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			label:              "Key of another project",
+			key:                "projects/other-project/0123456789abcdef.bin",
+			object:             &fakeS3Object{content: pdf, contentType: "application/pdf"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			seedProject(t, s, fake)
+			if test.object != nil {
+				fake.objects[test.key] = *test.object
+			}
+			if test.allowedContentTypes != nil {
+				s.Uploads.AllowedContentTypes = test.allowedContentTypes
+			}
+			if test.maxImageSize != 0 {
+				s.Uploads.MaxImageSize = test.maxImageSize
+			}
+
+			res, _ := s.confirmUploadHandler(context.Background(), jsonRequest(t, http.MethodPost, "/api/v1/projects/upload-confirm", models.UploadConfirmation{
+				PersonalWebsiteType: "Projects",
+				SortValue:           "Personal Website",
+				Key:                 test.key,
+			}))
+			if res.StatusCode != test.expectedStatusCode {
+				t.Fatalf("expected %d, got %d %s", test.expectedStatusCode, res.StatusCode, res.Body)
+			}
+
+			project := getProject(t, s, "Personal Website")
+			ref := project.GetMediaRef()
+			if !test.expectedCover {
+				if ref == nil || ref.Key != existingCoverKey {
+					t.Errorf("expected the existing cover to be kept, got %+v", ref)
+				}
+				rejected := test.expectedStatusCode == http.StatusUnsupportedMediaType || test.expectedStatusCode == http.StatusRequestEntityTooLarge
+				if _, ok := fake.objects[test.key]; ok && rejected {
+					t.Errorf("expected the rejected upload %s to be deleted", test.key)
+				}
+				return
+			}
+
+			if ref == nil || ref.Key != test.key {
+				t.Fatalf("expected the cover to be %s, got %+v", test.key, ref)
+			}
+			object, ok := fake.objects[test.key]
+			if !ok {
+				t.Fatalf("expected %s to be kept", test.key)
+			}
+			if ref.ContentType != test.object.contentType || ref.Size != int64(len(object.content)) {
+				t.Errorf("expected the reference to match the stored object, got %+v", ref)
+			}
+			if _, ok := fake.objects[existingCoverKey]; ok {
+				t.Errorf("expected the previous cover %s to be deleted", existingCoverKey)
+			}
+			if (len(project.MediaVariants) > 0) != test.expectedVariants {
+				t.Errorf("expected variants %v, got %+v", test.expectedVariants, project.MediaVariants)
+			}
+			for _, variant := range project.MediaVariants {
+				if _, ok := fake.objects[variant.GetRef().Key]; !ok {
+					t.Errorf("expected variant %s to be uploaded", variant.GetRef().Key)
+				}
+			}
+			if test.expectedContent != nil {
+				test.expectedContent(t, object.content)
+			}
+		})
+	}
+}
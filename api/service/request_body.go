@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// decodeRequestBody decodes the body of a create or update request into T.
+// multipart/form-data bodies are bound with parseFormData and return their
+// file parts, and JSON bodies, or bodies without a content type, are decoded.
+// Errors can be returned with uploadErrorResponse, other content types return
+// errUnsupportedMediaType.
+func decodeRequestBody[T any](request events.APIGatewayProxyRequest, uploads *UploadConfig) (*T, []models.FileData, error) {
+	mediaType, _, err := requestMediaType(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mediaType == formDataMediaType {
+		return parseFormData[T](request, uploads)
+	}
+	if mediaType != "" && mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil, nil, fmt.Errorf("%w: %s", errUnsupportedMediaType, mediaType)
+	}
+
+	var result T
+	decoder := json.NewDecoder(requestBodyReader(request))
+	if err := decoder.Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("failed to deserialize json: %w", err)
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return nil, nil, fmt.Errorf("failed to deserialize json: unexpected data after the body")
+	}
+	return &result, nil, nil
+}
+
+const formDataMediaType = "multipart/form-data"
+
+// requestMediaType returns the lower-cased media type of the request's
+// Content-Type and its parameters, or an empty media type if the header is
+// not set. A malformed header returns errUnsupportedMediaType.
+func requestMediaType(request events.APIGatewayProxyRequest) (string, map[string]string, error) {
+	contentType := headersOf(request).get("Content-Type")
+	if contentType == "" {
+		return "", nil, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil && !errors.Is(err, mime.ErrInvalidMediaParameter) {
+		return "", nil, fmt.Errorf("%w: %q: %v", errUnsupportedMediaType, contentType, err)
+	}
+	return mediaType, params, nil
+}
+
+// isFormData reports whether the Content-Type of the request is
+// multipart/form-data, whether or not API Gateway base64 encoded the body
+func isFormData(request events.APIGatewayProxyRequest) bool {
+	mediaType, _, err := requestMediaType(request)
+	return err == nil && mediaType == formDataMediaType
+}
+
+// requestBodyReader returns a reader over the body of the request that
+// decodes it as it is read if API Gateway base64 encoded it
+func requestBodyReader(request events.APIGatewayProxyRequest) io.Reader {
+	body := strings.NewReader(request.Body)
+	if request.IsBase64Encoded {
+		return base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+// requestBodySize returns the size of the body of the request once decoded
+func requestBodySize(request events.APIGatewayProxyRequest) int64 {
+	if request.IsBase64Encoded {
+		return int64(base64.StdEncoding.DecodedLen(len(request.Body)))
+	}
+	return int64(len(request.Body))
+}
+
+// fileParts returns the files uploaded as fieldName, and an error naming any
+// other file part since the request has nowhere to store it
+func fileParts(files []models.FileData, fieldName string) ([]models.FileData, error) {
+	var matching []models.FileData
+	for _, file := range files {
+		if file.FieldName != fieldName {
+			return nil, unexpectedFileError(file)
+		}
+		matching = append(matching, file)
+	}
+	return matching, nil
+}
+
+// checkNoFiles returns an error if a request that has no files uploaded any
+func checkNoFiles(files []models.FileData) error {
+	if len(files) > 0 {
+		return unexpectedFileError(files[0])
+	}
+	return nil
+}
+
+func unexpectedFileError(file models.FileData) error {
+	return fmt.Errorf("unexpected file %s in form field %s", file.Filename, file.FieldName)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// multipartRequest returns a base64 encoded multipart/form-data request with
+// fields in order, followed by a file part for each of files
+func multipartRequest(t *testing.T, fields [][2]string, files ...models.FileData) events.APIGatewayProxyRequest {
+	request := rawMultipartRequest(t, fields, files...)
+	request.Body = base64.StdEncoding.EncodeToString([]byte(request.Body))
+	request.IsBase64Encoded = true
+	return request
+}
+
+// rawMultipartRequest returns a multipartRequest whose body is not base64
+// encoded, as API Gateway sends bodies that are not binary media types
+func rawMultipartRequest(t *testing.T, fields [][2]string, files ...models.FileData) events.APIGatewayProxyRequest {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.FieldName, file.Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file.Content)
+	}
+	writer.Close()
+
+	return events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": writer.FormDataContentType()},
+		Body:    body.String(),
+	}
+}
+
+func TestDecodeRequestBody(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	uploads := &UploadConfig{
+		MaxBodySize:         1024,
+		MaxFileSize:         512,
+		AllowedContentTypes: mediatype.Detectable,
+	}
+	work := models.Work{
+		PersonalWebsiteType: "Work",
+		SortValue:           "2019-11-11",
+		Company:             "New Company",
+		Location:            models.Location{City: "New York", State: "NY"},
+		JobDescription:      []string{"Developed backend systems", "Optimized database queries"},
+	}
+
+	for _, test := range []struct {
+		label              string
+		request            events.APIGatewayProxyRequest
+		expectedWork       models.Work
+		expectedFiles      int
+		expectedStatusCode int
+	}{
+		{
+			label: "JSON",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"personalWebsiteType":"Work","sortValue":"2019-11-11","company":"New Company","location":{"city":"New York","state":"NY"},"jobDescription":["Developed backend systems","Optimized database queries"]}`,
+			},
+			expectedWork:       work,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "JSON without a content type",
+			request: events.APIGatewayProxyRequest{
+				Body: `{"personalWebsiteType":"Work","sortValue":"2019-11-11"}`,
+			},
+			expectedWork:       models.Work{PersonalWebsiteType: "Work", SortValue: "2019-11-11"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Form data with nested fields and a logo",
+			request: multipartRequest(t, [][2]string{
+				{"personalWebsiteType", "Work"},
+				{"sortValue", "2019-11-11"},
+				{"company", "New Company"},
+				{"location", `{"city":"New York","state":"NY"}`},
+				{"jobDescription", `["Developed backend systems","Optimized database queries"]`},
+			}, models.FileData{FieldName: companyLogoFieldName, Filename: "logo.png", Content: png}),
+			expectedWork:       work,
+			expectedFiles:      1,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Form data with dotted and indexed names",
+			request: multipartRequest(t, [][2]string{
+				{"personalWebsiteType", "Work"},
+				{"sortValue", "2019-11-11"},
+				{"company", "New Company"},
+				{"location.city", "New York"},
+				{"location.state", "NY"},
+				{"jobDescription[0]", "Developed backend systems"},
+				{"jobDescription[1]", "Optimized database queries"},
+			}),
+			expectedWork:       work,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Form data that is not base64 encoded",
+			request: rawMultipartRequest(t, [][2]string{
+				{"personalWebsiteType", "Work"},
+				{"sortValue", "2019-11-11"},
+			}, models.FileData{FieldName: companyLogoFieldName, Filename: "logo.png", Content: png}),
+			expectedWork:       models.Work{PersonalWebsiteType: "Work", SortValue: "2019-11-11"},
+			expectedFiles:      1,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Form data content type with different case",
+			request: func() events.APIGatewayProxyRequest {
+				request := rawMultipartRequest(t, [][2]string{{"sortValue", "2019-11-11"}})
+				request.Headers["Content-Type"] = strings.Replace(request.Headers["Content-Type"], "multipart/form-data", "Multipart/Form-Data", 1)
+				return request
+			}(),
+			expectedWork:       models.Work{SortValue: "2019-11-11"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Form data without a boundary",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "multipart/form-data"},
+				Body:    "--boundary--",
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			label: "Form data with invalid base64",
+			request: func() events.APIGatewayProxyRequest {
+				request := multipartRequest(t, [][2]string{{"sortValue", "2019-11-11"}})
+				request.Body = "!" + request.Body
+				return request
+			}(),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			label: "Base64 encoded JSON with a charset",
+			request: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Type": "application/json; charset=utf-8"},
+				Body:            base64.StdEncoding.EncodeToString([]byte(`{"sortValue":"2019-11-11"}`)),
+				IsBase64Encoded: true,
+			},
+			expectedWork:       models.Work{SortValue: "2019-11-11"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label: "Data after the JSON body",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"sortValue":"2019-11-11"} {}`,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			label: "Malformed content type",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "multipart/"},
+				Body:    "{}",
+			},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label: "Invalid nested field",
+			request: multipartRequest(t, [][2]string{
+				{"location", `New York`},
+			}),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			label: "Invalid JSON",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"sortValue":`,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			label: "Unsupported content type",
+			request: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Content-Type": "text/plain"},
+				Body:    "sortValue=2019-11-11",
+			},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label: "Base64 encoded body that is not form data",
+			request: events.APIGatewayProxyRequest{
+				Headers:         map[string]string{"Content-Type": "application/octet-stream"},
+				Body:            base64.StdEncoding.EncodeToString(png),
+				IsBase64Encoded: true,
+			},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			decoded, files, err := decodeRequestBody[models.Work](test.request, uploads)
+			if test.expectedStatusCode != http.StatusOK {
+				if statusCode := uploadErrorResponse(err).StatusCode; err == nil || statusCode != test.expectedStatusCode {
+					t.Errorf("expected %v, got %v (%v)", test.expectedStatusCode, statusCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*decoded, test.expectedWork) {
+				t.Errorf("expected %+v, got %+v", test.expectedWork, *decoded)
+			}
+			if len(files) != test.expectedFiles {
+				t.Errorf("expected %d files, got %d", test.expectedFiles, len(files))
+			}
+		})
+	}
+}
+
+func TestDecodeSkillsToolsFormData(t *testing.T) {
+	request := multipartRequest(t, [][2]string{
+		{"personalWebsiteType", "Skills"},
+		{"sortValue", "Software Development"},
+		{"categories", `[{"category":"Languages","list":["Go","TypeScript"]}]`},
+	})
+
+	skillsTools, files, err := decodeRequestBody[models.SkillsTools](request, &UploadConfig{MaxBodySize: 1024, MaxFileSize: 512})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []models.Category{{Category: "Languages", List: []string{"Go", "TypeScript"}}}
+	if !reflect.DeepEqual(skillsTools.Categories, expected) || len(files) != 0 {
+		t.Errorf("expected %+v, got %+v", expected, skillsTools.Categories)
+	}
+}
@@ -16,6 +16,10 @@ func resError(errorStatusCode int) (errRes string) {
 		message = "Bad Request: Invalid request"
 	case http.StatusNotFound:
 		message = "Resource not found"
+	case http.StatusRequestEntityTooLarge:
+		message = "Payload too large: File or request body exceeds the upload limit"
+	case http.StatusUnsupportedMediaType:
+		message = "Unsupported media type: File type is not allowed"
 	default:
 		message = "Unknown error"
 	}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+	"github.com/thomasmendez/personal-website-backend/api/resume"
+)
+
+// portfolio sections each resume section is rendered from
+var resumeSections = map[string]string{
+	resume.SectionWork:     sectionWork,
+	resume.SectionSkills:   sectionSkillsTools,
+	resume.SectionProjects: sectionProjects,
+}
+
+// getResumeHandler renders a resume from work, skillsTools and projects as
+// HTML, Markdown or PDF, chosen by the format query parameter or the Accept
+// header. The sections, title, template, from, to and projects query
+// parameters select what it includes, see resumeOptions.
+func (s *Service) getResumeHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, err := resumeFormat(request)
+	if errors.Is(err, errNotAcceptable) {
+		return notAcceptableResponse(err), nil
+	}
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+	opts, templateName, err := resumeOptions(request.QueryStringParameters)
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+
+	portfolio, stored, firstExpiry := s.getPortfolio(ctx)
+	if section := failedResumeSection(portfolio, opts.Sections); section != "" {
+		log.Printf("error in getting resume: %s failed", section)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, nil
+	}
+
+	content, err := resume.New(portfolio, opts)
+	if err != nil {
+		return mediaErrorResponse(http.StatusBadRequest, "%v", err), nil
+	}
+
+	// the template is part of the ETag, so an uploaded template changes it
+	source, err := s.Resume.TemplateSource(ctx, format, templateName)
+	if errors.Is(err, resume.ErrTemplateNotFound) {
+		return mediaErrorResponse(http.StatusNotFound, "%v", err), nil
+	}
+	var body bytes.Buffer
+	if err == nil {
+		err = resume.RenderSource(&body, format, templateName, source, content)
+	}
+	if err != nil {
+		log.Printf("error in rendering resume: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	etag := contentETag(format.ContentType(), firstExpiry, stored, opts, templateName, source)
+	res := s.Cache.cacheableResponse(request, body.Bytes(), format.ContentType(), etag, s.Cache.maxAgeUntil(firstExpiry))
+	if format == resume.PDF && res.Body != "" {
+		res.Body = base64.StdEncoding.EncodeToString(body.Bytes())
+		res.IsBase64Encoded = true
+	}
+	return res, nil
+}
+
+// resumeFormat returns the format of the format query parameter, md, html or
+// pdf, or negotiates it from the Accept header
+func resumeFormat(request events.APIGatewayProxyRequest) (resume.Format, error) {
+	if format := request.QueryStringParameters["format"]; format != "" {
+		format = strings.ToLower(format)
+		if format == "markdown" {
+			format = string(resume.Markdown)
+		}
+		for _, f := range resume.Formats {
+			if string(f) == format {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("unknown format %q, expected one of md, html or pdf", format)
+	}
+
+	offered := make([]string, len(resume.Formats))
+	for i, f := range resume.Formats {
+		offered[i] = strings.Split(f.ContentType(), ";")[0]
+	}
+	mediaType, err := negotiateFormat(request, offered)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range resume.Formats {
+		if strings.HasPrefix(f.ContentType(), mediaType) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", errNotAcceptable, mediaType)
+}
+
+// resumeOptions reads the resume options and template name from the query
+// parameters. sections and projects are comma separated lists, and from and
+// to are dates such as 2019, 2019-06 or Jun 2019, where to includes all of
+// the period it names.
+func resumeOptions(params map[string]string) (resume.Options, string, error) {
+	opts := resume.Options{
+		Title:    strings.TrimSpace(params["title"]),
+		Sections: splitQueryList(params["sections"]),
+		Projects: splitQueryList(params["projects"]),
+	}
+
+	if from := params["from"]; from != "" {
+		start, _, ok := models.ParseDate(from)
+		if !ok {
+			return opts, "", fmt.Errorf("invalid from date %q", from)
+		}
+		opts.From = start
+	}
+	if to := params["to"]; to != "" {
+		_, end, ok := models.ParseDate(to)
+		if !ok {
+			return opts, "", fmt.Errorf("invalid to date %q", to)
+		}
+		opts.To = end
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.From.Before(opts.To) {
+		return opts, "", fmt.Errorf("from must be before to")
+	}
+
+	templateName := params["template"]
+	if templateName != "" && !resume.ValidTemplateName(templateName) {
+		return opts, "", fmt.Errorf("invalid template name %q", templateName)
+	}
+	return opts, templateName, nil
+}
+
+// failedResumeSection returns the portfolio section a resume with sections
+// needs that could not be loaded, or "" if they all loaded
+func failedResumeSection(portfolio models.Portfolio, sections []string) string {
+	if len(sections) == 0 {
+		sections = resume.Sections
+	}
+	for _, portfolioError := range portfolio.Errors {
+		for _, section := range sections {
+			if resumeSections[section] == portfolioError.Section {
+				return portfolioError.Section
+			}
+		}
+	}
+	return ""
+}
+
+func splitQueryList(value string) []string {
+	var list []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
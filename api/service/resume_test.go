@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+	"github.com/thomasmendez/personal-website-backend/api/resume"
+)
+
+func TestResumeFormat(t *testing.T) {
+	for _, test := range []struct {
+		label          string
+		params         map[string]string
+		accept         string
+		expectedFormat resume.Format
+		expectError    bool
+		notAcceptable  bool
+	}{
+		{
+			label:          "HTML by default",
+			expectedFormat: resume.HTML,
+		},
+		{
+			label:          "Format parameter",
+			params:         map[string]string{"format": "PDF"},
+			accept:         "text/html",
+			expectedFormat: resume.PDF,
+		},
+		{
+			label:          "Markdown parameter",
+			params:         map[string]string{"format": "markdown"},
+			expectedFormat: resume.Markdown,
+		},
+		{
+			label:          "Accept header",
+			accept:         "text/markdown",
+			expectedFormat: resume.Markdown,
+		},
+		{
+			label:          "Accepted PDF",
+			accept:         "application/pdf, */*;q=0.1",
+			expectedFormat: resume.PDF,
+		},
+		{
+			label:       "Unknown format parameter",
+			params:      map[string]string{"format": "docx"},
+			expectError: true,
+		},
+		{
+			label:         "Unacceptable",
+			accept:        "application/json",
+			expectError:   true,
+			notAcceptable: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{
+				QueryStringParameters: test.params,
+				Headers:               map[string]string{"Accept": test.accept},
+			}
+			format, err := resumeFormat(request)
+			if test.expectError {
+				if err == nil || errors.Is(err, errNotAcceptable) != test.notAcceptable {
+					t.Errorf("expected an error, not acceptable %v, got %v", test.notAcceptable, err)
+				}
+				return
+			}
+			if err != nil || format != test.expectedFormat {
+				t.Errorf("expected %v, got %v (%v)", test.expectedFormat, format, err)
+			}
+		})
+	}
+}
+
+func TestResumeOptions(t *testing.T) {
+	for _, test := range []struct {
+		label            string
+		params           map[string]string
+		expectedOpts     resume.Options
+		expectedTemplate string
+		expectError      bool
+	}{
+		{
+			label: "No parameters",
+		},
+		{
+			label: "Every parameter",
+			params: map[string]string{
+				"title":    " Thomas Mendez ",
+				"sections": "work, projects,",
+				"projects": "Personal Website,Game",
+				"from":     "2019-06",
+				"to":       "2024",
+				"template": "modern",
+			},
+			expectedOpts: resume.Options{
+				Title:    "Thomas Mendez",
+				Sections: []string{"work", "projects"},
+				Projects: []string{"Personal Website", "Game"},
+				From:     time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expectedTemplate: "modern",
+		},
+		{
+			label:       "Invalid date",
+			params:      map[string]string{"from": "last year"},
+			expectError: true,
+		},
+		{
+			label:       "From after to",
+			params:      map[string]string{"from": "2024", "to": "2019"},
+			expectError: true,
+		},
+		{
+			label:       "Invalid template name",
+			params:      map[string]string{"template": "../default"},
+			expectError: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			opts, templateName, err := resumeOptions(test.params)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(opts, test.expectedOpts) || templateName != test.expectedTemplate {
+				t.Errorf("expected %+v %q, got %+v %q", test.expectedOpts, test.expectedTemplate, opts, templateName)
+			}
+		})
+	}
+}
+
+func TestFailedResumeSection(t *testing.T) {
+	portfolio := models.Portfolio{Errors: []models.PortfolioError{{Section: sectionSkillsTools}}}
+
+	if section := failedResumeSection(portfolio, nil); section != sectionSkillsTools {
+		t.Errorf("expected %s to fail every section, got %q", sectionSkillsTools, section)
+	}
+	if section := failedResumeSection(portfolio, []string{resume.SectionWork}); section != "" {
+		t.Errorf("expected a resume without skills to render, got %q", section)
+	}
+}
@@ -64,5 +64,65 @@ func addRoutes(s *Service) *[]RouteHandler {
 			Method:  http.MethodDelete,
 			Handler: s.deleteProjectHandler,
 		},
+		{
+			Route:   "/api/v1/projects/media",
+			Method:  http.MethodPost,
+			Handler: s.addProjectMediaHandler,
+		},
+		{
+			Route:   "/api/v1/projects/media",
+			Method:  http.MethodPut,
+			Handler: s.reorderProjectMediaHandler,
+		},
+		{
+			Route:   "/api/v1/projects/media",
+			Method:  http.MethodDelete,
+			Handler: s.removeProjectMediaHandler,
+		},
+		{
+			Route:   "/api/v1/projects/upload-url",
+			Method:  http.MethodPost,
+			Handler: s.getUploadURLHandler,
+		},
+		{
+			Route:   "/api/v1/projects/upload-confirm",
+			Method:  http.MethodPost,
+			Handler: s.confirmUploadHandler,
+		},
+		{
+			Route:   "/api/v1/portfolio",
+			Method:  http.MethodGet,
+			Handler: s.getPortfolioHandler,
+		},
+		{
+			Route:   "/api/v1/resume",
+			Method:  http.MethodGet,
+			Handler: s.getResumeHandler,
+		},
+		{
+			Route:   "/api/v1/export",
+			Method:  http.MethodPost,
+			Handler: s.exportHandler,
+		},
+		{
+			Route:   "/api/v1/import",
+			Method:  http.MethodPost,
+			Handler: s.importHandler,
+		},
+		{
+			Route:   "/api/v1/health",
+			Method:  http.MethodGet,
+			Handler: s.healthHandler,
+		},
+		{
+			Route:   "/api/v1/ready",
+			Method:  http.MethodGet,
+			Handler: s.readyHandler,
+		},
+		{
+			Route:   "/api/v1/version",
+			Method:  http.MethodGet,
+			Handler: s.versionHandler,
+		},
 	}
 }
@@ -13,12 +13,20 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/thomasmendez/personal-website-backend/api/bucket"
 	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/resume"
+	"github.com/thomasmendez/personal-website-backend/api/version"
 )
 
 type Service struct {
 	DB        *database.Database
 	S3        *bucket.Bucket
 	TableName string
+	Env       string
+	Version   version.Info
+	Cors      *CorsConfig
+	Cache     *CacheConfig
+	Uploads   *UploadConfig
+	Resume    *resume.Renderer
 	Routes    *[]RouteHandler
 }
 
@@ -78,18 +86,38 @@ func NewService() *Service {
 		DB:        database.NewDatabase(awsConfig, options),
 		S3:        bucket.NewBucket(awsConfig, s3BucketName),
 		TableName: tableName,
+		Env:       env,
+		Version:   version.Get(env),
+		Cors:      newCorsConfig(env),
+		Cache:     newCacheConfig(),
+		Uploads:   newUploadConfig(),
 	}
 
+	configureMediaURLs(s.S3)
+	s.Resume = &resume.Renderer{Store: s.S3}
+
 	s.Routes = addRoutes(s)
 
 	return s
 }
 
 func (s *Service) HandleRoute(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	origin := headersOf(request).get("Origin")
+
 	for _, route := range *s.Routes {
-		if request.Path == route.Route && request.HTTPMethod == route.Method {
+		if request.Path != route.Route {
+			continue
+		}
+		if request.HTTPMethod == http.MethodOptions {
+			return events.APIGatewayProxyResponse{
+				Headers:    s.Cors.headers(origin, true),
+				StatusCode: http.StatusNoContent,
+			}, nil
+		}
+		if request.HTTPMethod == route.Method {
 			proxyResponse, err := route.Handler(ctx, request)
-			proxyResponse.Headers = s.addProxyHeaders(os.Getenv("ENV"))
+			proxyResponse.Headers = addProxyHeaders(proxyResponse.Headers, s.Cors.headers(origin, false))
+			proxyResponse.Headers = addProxyHeaders(proxyResponse.Headers, s.versionHeaders())
 			return proxyResponse, err
 		}
 	}
@@ -100,37 +128,23 @@ func (s *Service) HandleRoute(ctx context.Context, request events.APIGatewayProx
 	res, _ := json.Marshal(errRes)
 
 	return events.APIGatewayProxyResponse{
-		Headers:    s.addProxyHeaders(os.Getenv("ENV")),
+		Headers:    addProxyHeaders(s.Cors.headers(origin, false), s.versionHeaders()),
 		StatusCode: http.StatusNotFound,
 		Body:       string(res),
 	}, nil
 }
 
-func (s *Service) addProxyHeaders(env string) map[string]string {
-	switch env {
-	case "Dev":
-		return map[string]string{
-			"Access-Control-Allow-Origin":  "http://localhost:5173",
-			"Access-Control-Allow-Headers": "*",
-			"Access-Control-Allow-Methods": "*",
-		}
-	case "Stg":
-		return map[string]string{
-			"Access-Control-Allow-Origin":  os.Getenv("ORIGIN"),
-			"Access-Control-Allow-Headers": os.Getenv("HEADERS"),
-			"Access-Control-Allow-Methods": os.Getenv("METHODS"),
-		}
-	case "Prd":
-		return map[string]string{
-			"Access-Control-Allow-Origin":  os.Getenv("ORIGIN"),
-			"Access-Control-Allow-Headers": os.Getenv("HEADERS"),
-			"Access-Control-Allow-Methods": os.Getenv("METHODS"),
-		}
-	default:
-		return map[string]string{
-			"Access-Control-Allow-Origin":  "*",
-			"Access-Control-Allow-Headers": "*",
-			"Access-Control-Allow-Methods": "*",
+// addProxyHeaders merges the proxy headers into the headers set by a handler.
+// Vary lists the request headers of both.
+func addProxyHeaders(headers map[string]string, proxyHeaders map[string]string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string, len(proxyHeaders))
+	}
+	for key, value := range proxyHeaders {
+		if vary := headers[key]; key == "Vary" && vary != "" {
+			value = vary + ", " + value
 		}
+		headers[key] = value
 	}
+	return headers
 }
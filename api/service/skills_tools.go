@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/thomasmendez/personal-website-backend/api/database"
@@ -15,6 +16,11 @@ import (
 )
 
 func (s *Service) getSkillsToolsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, err := negotiateFormat(request, responseFormats)
+	if err != nil {
+		return notAcceptableResponse(err), nil
+	}
+
 	skillsTools, err := database.GetSkillsTools(ctx, s.DB.Client, s.TableName)
 
 	if err != nil {
@@ -29,26 +35,22 @@ func (s *Service) getSkillsToolsHandler(ctx context.Context, request events.APIG
 		}, err
 	}
 
-	skillsToolsJson, err := json.Marshal(skillsTools)
+	skillsToolsBody, err := encodeResponse(format, skillsTools)
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Body:       string(skillsToolsJson),
-	}, err
+	return s.Cache.cacheableResponse(request, skillsToolsBody, format, contentETag(format, time.Time{}, skillsTools), s.Cache.MaxAge), err
 }
 
 func (s *Service) postSkillsToolsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var newSkillsTools models.SkillsTools
-	err := json.Unmarshal([]byte(request.Body), &newSkillsTools)
+	newSkillsTools, files, err := decodeRequestBody[models.SkillsTools](request, s.Uploads)
+	if err == nil {
+		err = checkNoFiles(files)
+	}
 	if err != nil {
 		log.Printf("err: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       resError(http.StatusBadRequest),
-		}, err
+		return uploadErrorResponse(err), err
 	}
 
-	err = s.validateSkillTools(newSkillsTools)
+	err = s.validateSkillTools(*newSkillsTools)
 	if err != nil {
 		log.Print(err.Error())
 		errRes := ErrorResponse{
@@ -61,7 +63,7 @@ func (s *Service) postSkillsToolsHandler(ctx context.Context, request events.API
 		}, nil
 	}
 
-	skillsTools, err := database.PostSkillsTools(ctx, s.DB.Client, s.TableName, newSkillsTools)
+	skillsTools, err := database.PostSkillsTools(ctx, s.DB.Client, s.TableName, *newSkillsTools)
 
 	if err != nil {
 		log.Print(err.Error())
@@ -85,17 +87,16 @@ func (s *Service) postSkillsToolsHandler(ctx context.Context, request events.API
 
 func (s *Service) updateSkillsToolsHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
-	var updateSkillsTools models.SkillsTools
-	err := json.Unmarshal([]byte(request.Body), &updateSkillsTools)
+	updateSkillsTools, files, err := decodeRequestBody[models.SkillsTools](request, s.Uploads)
+	if err == nil {
+		err = checkNoFiles(files)
+	}
 	if err != nil {
 		log.Printf("err: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       resError(http.StatusBadRequest),
-		}, err
+		return uploadErrorResponse(err), err
 	}
 
-	err = s.validateSkillTools(updateSkillsTools)
+	err = s.validateSkillTools(*updateSkillsTools)
 	if err != nil {
 		log.Print(err.Error())
 		errRes := ErrorResponse{
@@ -108,7 +109,7 @@ func (s *Service) updateSkillsToolsHandler(ctx context.Context, request events.A
 		}, nil
 	}
 
-	skillsTools, err := database.UpdateSkillsTools(ctx, s.DB.Client, s.TableName, updateSkillsTools)
+	skillsTools, err := database.UpdateSkillsTools(ctx, s.DB.Client, s.TableName, *updateSkillsTools)
 
 	if err != nil {
 		log.Print(err.Error())
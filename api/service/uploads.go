@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/mediatype"
+)
+
+const (
+	// API Gateway limits request payloads to 6 MB
+	defaultMaxBodySize int64 = 6 << 20
+	defaultMaxFileSize int64 = 5 << 20
+	// presigned uploads go straight to S3, so they are not limited by API Gateway
+	defaultMaxUploadSize int64 = 100 << 20
+	// images are read and decoded in memory when their upload is confirmed
+	defaultMaxImageSize int64 = 20 << 20
+)
+
+var (
+	errBodyTooLarge         = errors.New("request body is too large")
+	errFileTooLarge         = errors.New("file is too large")
+	errUnsupportedMediaType = mediatype.ErrUnsupported
+)
+
+type UploadConfig struct {
+	// MaxBodySize is the largest decoded multipart body
+	MaxBodySize int64
+	// MaxFileSize is the largest file in a multipart body
+	MaxFileSize int64
+	// MaxUploadSize is the largest file uploaded with a presigned URL
+	MaxUploadSize int64
+	// MaxImageSize is the largest image or SVG uploaded with a presigned URL,
+	// which are processed rather than stored as uploaded
+	MaxImageSize int64
+	// AllowedContentTypes are the detected content types files can have
+	AllowedContentTypes []string
+	// StrictFormFields rejects form fields the request body does not have
+	// instead of skipping them
+	StrictFormFields bool
+}
+
+// newUploadConfig reads the upload limits from MAX_BODY_SIZE, MAX_FILE_SIZE,
+// MAX_UPLOAD_SIZE and MAX_IMAGE_SIZE in bytes, the comma separated allowlist of content
+// types from ALLOWED_CONTENT_TYPES and whether unknown form fields are
+// rejected from STRICT_FORM_FIELDS. Every detectable type is allowed by default.
+func newUploadConfig() *UploadConfig {
+	uploads := &UploadConfig{
+		MaxBodySize:   envSize("MAX_BODY_SIZE", defaultMaxBodySize),
+		MaxFileSize:   envSize("MAX_FILE_SIZE", defaultMaxFileSize),
+		MaxUploadSize: envSize("MAX_UPLOAD_SIZE", defaultMaxUploadSize),
+		MaxImageSize:  envSize("MAX_IMAGE_SIZE", defaultMaxImageSize),
+	}
+
+	contentTypes := os.Getenv("ALLOWED_CONTENT_TYPES")
+	allowed, err := mediatype.ParseAllowed(contentTypes)
+	if err != nil {
+		log.Fatalf("error in configuration: ALLOWED_CONTENT_TYPES can only contain %v \n Currently: %v", mediatype.Detectable, contentTypes)
+	}
+	uploads.AllowedContentTypes = allowed
+
+	if strict := os.Getenv("STRICT_FORM_FIELDS"); strict != "" {
+		strictFormFields, err := strconv.ParseBool(strict)
+		if err != nil {
+			log.Fatalf("error in configuration: STRICT_FORM_FIELDS must be a boolean \n Currently: %v", strict)
+		}
+		uploads.StrictFormFields = strictFormFields
+	}
+
+	return uploads
+}
+
+func envSize(key string, defaultSize int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultSize
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		log.Fatalf("error in configuration: %s must be a positive number of bytes \n Currently: %v", key, value)
+	}
+	return size
+}
+
+// allows reports whether files of contentType can be uploaded
+func (u *UploadConfig) allows(contentType string) bool {
+	return slices.Contains(u.AllowedContentTypes, contentType)
+}
+
+// maxUploadSize returns the largest file of contentType uploaded with a
+// presigned URL. Images and SVGs are read in full to be processed, so they are
+// limited to MaxImageSize.
+func (u *UploadConfig) maxUploadSize(contentType string) int64 {
+	if imaging.IsProcessable(contentType) || contentType == "image/svg+xml" {
+		return min(u.MaxImageSize, u.MaxUploadSize)
+	}
+	return u.MaxUploadSize
+}
+
+// checkFile returns an error wrapping errUnsupportedMediaType or
+// errFileTooLarge if a file of contentType and size cannot be uploaded
+func (u *UploadConfig) checkFile(contentType string, size int64, maxSize int64) error {
+	if !u.allows(contentType) {
+		return fmt.Errorf("%w: %s", errUnsupportedMediaType, contentType)
+	}
+	if size > maxSize {
+		return fmt.Errorf("%w: %d bytes is over the limit of %d bytes", errFileTooLarge, size, maxSize)
+	}
+	return nil
+}
+
+// uploadErrorResponse returns 413 or 415 for errors caused by the upload
+// limits and 400 for any other error in parsing the request. Unknown form
+// fields are listed in the message.
+func uploadErrorResponse(err error) events.APIGatewayProxyResponse {
+	statusCode := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errBodyTooLarge), errors.Is(err, errFileTooLarge):
+		statusCode = http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnsupportedMediaType):
+		statusCode = http.StatusUnsupportedMediaType
+	}
+	if errors.Is(err, errUnknownFormField) {
+		return mediaErrorResponse(statusCode, "%v", err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       resError(statusCode),
+	}
+}
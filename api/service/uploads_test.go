@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+func formDataRequest(t *testing.T, filename string, content []byte) events.APIGatewayProxyRequest {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("sortValue", "Personal Website"); err != nil {
+		t.Fatal(err)
+	}
+	part, err := writer.CreateFormFile("mediaLink", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	return events.APIGatewayProxyRequest{
+		Headers:         map[string]string{"Content-Type": writer.FormDataContentType()},
+		Body:            base64.StdEncoding.EncodeToString(body.Bytes()),
+		IsBase64Encoded: true,
+	}
+}
+
+func TestParseFormDataLimits(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+	uploads := &UploadConfig{
+		MaxBodySize:         1024,
+		MaxFileSize:         200,
+		AllowedContentTypes: []string{"image/png", "image/svg+xml"},
+	}
+
+	for _, test := range []struct {
+		label              string
+		request            events.APIGatewayProxyRequest
+		expectedStatusCode int
+	}{
+		{
+			label:              "Allowed file",
+			request:            formDataRequest(t, "image.png", png),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			label:              "File over the limit",
+			request:            formDataRequest(t, "image.png", append(png, make([]byte, 200)...)),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			label:              "Body over the limit",
+			request:            formDataRequest(t, "image.png", append(png, make([]byte, 2048)...)),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			label:              "Type not in allowlist",
+			request:            formDataRequest(t, "image.gif", []byte("GIF89a\x01\x00\x01\x00")),
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:              "Unknown type",
+			request:            formDataRequest(t, "notes.txt", []byte("hello")),
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			label:              "Malformed SVG",
+			request:            formDataRequest(t, "image.svg", []byte(`<svg><g></svg>`)),
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			project, files, err := parseFormData[models.Project](test.request, uploads)
+			if test.expectedStatusCode == http.StatusOK {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if project.SortValue != "Personal Website" || len(files) != 1 || files[0].ContentType != "image/png" {
+					t.Errorf("unexpected result: %v %v", project.SortValue, files)
+				}
+				return
+			}
+			if statusCode := uploadErrorResponse(err).StatusCode; statusCode != test.expectedStatusCode {
+				t.Errorf("expected %v, got %v (%v)", test.expectedStatusCode, statusCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (s *Service) versionHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	versionJson, err := json.Marshal(s.Version)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(versionJson),
+	}, err
+}
+
+// versionHeaders identifies the deployed build on every response
+func (s *Service) versionHeaders() map[string]string {
+	return map[string]string{
+		"X-Api-Version": s.Version.Version,
+		"X-Api-Commit":  s.Version.Commit,
+	}
+}
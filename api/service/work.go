@@ -15,6 +15,11 @@ import (
 )
 
 func (s *Service) getWorkHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	format, err := negotiateFormat(request, responseFormats)
+	if err != nil {
+		return notAcceptableResponse(err), nil
+	}
+
 	work, err := database.GetWork(ctx, s.DB.Client, s.TableName)
 
 	if err != nil {
@@ -29,27 +34,30 @@ func (s *Service) getWorkHandler(ctx context.Context, request events.APIGatewayP
 		}, err
 	}
 
-	workJson, err := json.Marshal(work)
+	// the ETag is of the work as stored, see contentETag
+	stored, _ := json.Marshal(work)
+	firstExpiry := s.presignCompanyLogos(ctx, work)
+	etag := contentETag(format, firstExpiry, json.RawMessage(stored))
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Body:       string(workJson),
-	}, err
+	workBody, err := encodeResponse(format, work)
+
+	return s.Cache.cacheableResponse(request, workBody, format, etag, s.Cache.maxAgeUntil(firstExpiry)), err
 }
 
 func (s *Service) postWorkHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
-	var newWork models.Work
-	err := json.Unmarshal([]byte(request.Body), &newWork)
+	newWork, files, err := decodeRequestBody[models.Work](request, s.Uploads)
 	if err != nil {
 		log.Printf("err: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       resError(http.StatusBadRequest),
-		}, err
+		return uploadErrorResponse(err), err
+	}
+	logo, err := companyLogoFile(files)
+	if err != nil {
+		log.Printf("error in company logo: %v", err)
+		return uploadErrorResponse(err), err
 	}
 
-	err = s.validateWork(newWork)
+	err = s.validateWork(*newWork)
 	if err != nil {
 		log.Print(err.Error())
 		errRes := ErrorResponse{
@@ -62,10 +70,22 @@ func (s *Service) postWorkHandler(ctx context.Context, request events.APIGateway
 		}, nil
 	}
 
-	work, err := database.PostWork(ctx, s.DB.Client, s.TableName, newWork)
+	s.setCompanyLogoRef(newWork, models.Work{})
+	if logo != nil {
+		if err := s.uploadCompanyLogo(ctx, newWork, *logo); err != nil {
+			log.Printf("failed to upload company logo to S3: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       resError(http.StatusInternalServerError),
+			}, err
+		}
+	}
+
+	work, err := database.PostWork(ctx, s.DB.Client, s.TableName, *newWork)
 
 	if err != nil {
 		log.Print(err.Error())
+		s.deleteUnreferencedLogo(ctx, *newWork, models.Work{})
 		errRes := ErrorResponse{
 			Message: fmt.Sprintf("There was an error in inserting work with sortValue of: %s", newWork.SortValue),
 		}
@@ -76,6 +96,7 @@ func (s *Service) postWorkHandler(ctx context.Context, request events.APIGateway
 		}, err
 	}
 
+	s.presignCompanyLogo(ctx, &work)
 	workJson, err := json.Marshal(work)
 
 	return events.APIGatewayProxyResponse{
@@ -99,17 +120,18 @@ func (s *Service) validateWork(work models.Work) error {
 
 func (s *Service) updateWorkHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
-	var updateWork models.Work
-	err := json.Unmarshal([]byte(request.Body), &updateWork)
+	updateWork, files, err := decodeRequestBody[models.Work](request, s.Uploads)
 	if err != nil {
 		log.Printf("err: %v", err)
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       resError(http.StatusBadRequest),
-		}, err
+		return uploadErrorResponse(err), err
+	}
+	logo, err := companyLogoFile(files)
+	if err != nil {
+		log.Printf("error in company logo: %v", err)
+		return uploadErrorResponse(err), err
 	}
 
-	err = s.validateWork(updateWork)
+	err = s.validateWork(*updateWork)
 	if err != nil {
 		log.Print(err.Error())
 		errRes := ErrorResponse{
@@ -122,10 +144,32 @@ func (s *Service) updateWorkHandler(ctx context.Context, request events.APIGatew
 		}, nil
 	}
 
-	work, err := database.UpdateWork(ctx, s.DB.Client, s.TableName, updateWork)
+	var existingWork models.Work
+	err = database.GetItem(ctx, s.DB.Client, s.TableName, updateWork.PersonalWebsiteType, updateWork.SortValue, &existingWork)
+	if err != nil {
+		log.Printf("error in getting work: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       resError(http.StatusInternalServerError),
+		}, err
+	}
+
+	s.setCompanyLogoRef(updateWork, existingWork)
+	if logo != nil {
+		if err := s.uploadCompanyLogo(ctx, updateWork, *logo); err != nil {
+			log.Printf("failed to upload company logo to S3: %v", err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       resError(http.StatusInternalServerError),
+			}, err
+		}
+	}
+
+	work, err := database.UpdateWork(ctx, s.DB.Client, s.TableName, *updateWork)
 
 	if err != nil {
 		log.Print(err.Error())
+		s.deleteUnreferencedLogo(ctx, *updateWork, existingWork)
 		errRes := ErrorResponse{
 			Message: fmt.Sprintf("There was an error in updating work with sortValue of: %s", updateWork.SortValue),
 		}
@@ -136,6 +180,10 @@ func (s *Service) updateWorkHandler(ctx context.Context, request events.APIGatew
 		}, err
 	}
 
+	// the previous logo is only deleted once the work no longer references it
+	s.deleteUnreferencedLogo(ctx, existingWork, work)
+
+	s.presignCompanyLogo(ctx, &work)
 	workJson, err := json.Marshal(work)
 
 	return events.APIGatewayProxyResponse{
@@ -158,6 +206,12 @@ func (s *Service) deleteWorkHandler(ctx context.Context, request events.APIGatew
 	var existingWork models.Work
 	err = database.GetItem(ctx, s.DB.Client, s.TableName, deleteWork.PersonalWebsiteType, deleteWork.SortValue, &existingWork)
 
+	// the logo is stored as a reference while requests contain its resolved URL,
+	// so it is not compared
+	logoWork := existingWork
+	deleteWork.CompanyLogo, deleteWork.CompanyLogoRef = nil, nil
+	existingWork.CompanyLogo, existingWork.CompanyLogoRef = nil, nil
+
 	if !reflect.DeepEqual(deleteWork, existingWork) {
 		log.Printf("err: %v", err)
 		return events.APIGatewayProxyResponse{
@@ -180,6 +234,8 @@ func (s *Service) deleteWorkHandler(ctx context.Context, request events.APIGatew
 		}, err
 	}
 
+	s.deleteUnreferencedLogo(ctx, logoWork, models.Work{})
+
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Body:       "Resource was successfully deleted",
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thomasmendez/personal-website-backend/api/bucket"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+// form name of the company logo file of a work request
+const companyLogoFieldName = "companyLogo"
+
+// companyLogoFile returns the logo file of a work request, or nil if there is
+// none. Other file parts and logos that are not images return errors that can
+// be returned with uploadErrorResponse.
+func companyLogoFile(files []models.FileData) (*models.FileData, error) {
+	logos, err := fileParts(files, companyLogoFieldName)
+	if err != nil || len(logos) == 0 {
+		return nil, err
+	}
+	logo := logos[len(logos)-1]
+	if !slices.Contains(bucket.LogoContentTypes, logo.ContentType) {
+		return nil, fmt.Errorf("%w: company logo %s must be one of %v", errUnsupportedMediaType, logo.Filename, bucket.LogoContentTypes)
+	}
+	return &logo, nil
+}
+
+// setCompanyLogoRef replaces the companyLogo of a request, a URL the API
+// returned or an external link, with its reference. A logo existingWork
+// already has keeps its stored reference, and without a companyLogo the logo
+// of existingWork is kept. An empty companyLogo clears it.
+func (s *Service) setCompanyLogoRef(work *models.Work, existingWork models.Work) {
+	work.CompanyLogoRef = nil
+	if work.CompanyLogo == nil {
+		work.CompanyLogoRef = existingWork.GetCompanyLogoRef()
+		return
+	}
+	ref := s.S3.ParseMediaLink(*work.CompanyLogo)
+	work.CompanyLogo = nil
+	if existingRef := existingWork.GetCompanyLogoRef(); ref.IsS3() && existingRef.IsS3() && ref.Key == existingRef.Key {
+		ref = existingRef
+	}
+	work.CompanyLogoRef = ref
+}
+
+// uploadCompanyLogo uploads the logo of work with its metadata stripped and
+// sets the work's logo to it
+func (s *Service) uploadCompanyLogo(ctx context.Context, work *models.Work, file models.FileData) error {
+	log.Printf("uploading company logo: %s of work %s to S3", file.Filename, work.SortValue)
+	ref, err := s.S3.UploadCompanyLogo(ctx, work.SortValue, file)
+	if err != nil {
+		return err
+	}
+	work.CompanyLogo = nil
+	work.CompanyLogoRef = ref
+	return nil
+}
+
+// deleteUnreferencedLogo deletes the S3 object of the logo of work unless
+// keep references it. Logos outside the work's prefix are never deleted,
+// since companyLogos set by clients may refer to media of other items. Errors
+// are logged since the work is already saved.
+func (s *Service) deleteUnreferencedLogo(ctx context.Context, work models.Work, keep models.Work) {
+	ref := work.GetCompanyLogoRef()
+	if !ref.IsS3() {
+		return
+	}
+	if keepRef := keep.GetCompanyLogoRef(); keepRef.IsS3() && keepRef.Key == ref.Key {
+		return
+	}
+	if !strings.HasPrefix(ref.Key, bucket.KeyPrefix(bucket.WorkMediaPrefix, work.SortValue)+"/") {
+		log.Printf("not deleting company logo %s outside of work %s", ref.Key, work.SortValue)
+		return
+	}
+	log.Printf("deleting unreferenced company logo %s of work %s", ref.Key, work.SortValue)
+	if err := s.deleteMediaFile(ctx, ref.Key); err != nil {
+		log.Printf("error in deleting unreferenced company logo: %v", err)
+	}
+}
+
+// presignCompanyLogos sets the companyLogo of work to the URL it is served
+// from and returns when the first of them is regenerated
+func (s *Service) presignCompanyLogos(ctx context.Context, work []models.Work) (firstExpiry time.Time) {
+	for i := range work {
+		firstExpiry = earliest(firstExpiry, s.presignCompanyLogo(ctx, &work[i]))
+	}
+	return firstExpiry
+}
+
+func (s *Service) presignCompanyLogo(ctx context.Context, work *models.Work) (expires time.Time) {
+	if ref := work.GetCompanyLogoRef(); ref != nil {
+		var companyLogo string
+		companyLogo, expires = s.resolveMediaLink(ctx, ref)
+		work.CompanyLogo = &companyLogo
+	}
+	return expires
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/thomasmendez/personal-website-backend/api/database"
+	"github.com/thomasmendez/personal-website-backend/api/imaging"
+	"github.com/thomasmendez/personal-website-backend/api/models"
+)
+
+const existingLogoKey = "work/2019-06-11-eaad0378652fa400/existing.png"
+
+func TestUploadCompanyLogo(t *testing.T) {
+	for _, test := range []struct {
+		label       string
+		file        models.FileData
+		expectError error
+	}{
+		{
+			label: "Image",
+			file:  models.FileData{Filename: "logo.png", Content: testPNG(t, 8, 8, color.White), ContentType: "image/png"},
+		},
+		{
+			label: "JPEG keeps its orientation",
+			file:  models.FileData{Filename: "logo.jpg", Content: testJPEG(t, 8, 4, color.White, 6), ContentType: "image/jpeg"},
+		},
+		{
+			label:       "Not an image",
+			file:        models.FileData{Filename: "logo.pdf", Content: []byte("%PDF-1.7\n"), ContentType: "application/pdf"},
+			expectError: errUnsupportedMediaType,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			work := models.Work{PersonalWebsiteType: "Work", SortValue: "2019-06-11"}
+
+			err := s.uploadCompanyLogo(context.Background(), &work, test.file)
+			if test.expectError != nil {
+				if !errors.Is(err, test.expectError) || len(fake.objects) != 0 {
+					t.Errorf("expected %v and nothing uploaded, got %v with %d objects", test.expectError, err, len(fake.objects))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ref := work.GetCompanyLogoRef()
+			if ref == nil || !strings.HasPrefix(ref.Key, "work/2019-06-11-eaad0378652fa400/") {
+				t.Fatalf("expected the logo under work/2019-06-11-eaad0378652fa400/, got %+v", ref)
+			}
+			object, ok := fake.objects[ref.Key]
+			if !ok || work.CompanyLogo != nil {
+				t.Fatalf("expected %s to be uploaded and companyLogo cleared", ref.Key)
+			}
+			if test.file.ContentType == "image/jpeg" && imaging.JPEGOrientation(object.content) != 6 {
+				t.Errorf("expected the logo to keep orientation 6, got %d", imaging.JPEGOrientation(object.content))
+			}
+		})
+	}
+}
+
+func TestUpdateWorkLogo(t *testing.T) {
+	for _, test := range []struct {
+		label string
+		// request returns the update of the seeded work
+		request      func(t *testing.T) events.APIGatewayProxyRequest
+		expectedKept bool
+		expectedLogo bool
+	}{
+		{
+			label: "No companyLogo keeps the logo",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return jsonRequest(t, http.MethodPut, "/api/v1/work", map[string]any{
+					"personalWebsiteType": "Work",
+					"sortValue":           "2019-06-11",
+					"jobTitle":            "Renamed",
+					"jobDescription":      []string{"Developed backend systems"},
+				})
+			},
+			expectedKept: true,
+			expectedLogo: true,
+		},
+		{
+			label: "Form without companyLogo keeps the logo",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return multipartRequest(t, [][2]string{
+					{"personalWebsiteType", "Work"},
+					{"sortValue", "2019-06-11"},
+					{"jobTitle", "Renamed"},
+					{"jobDescription", `["Developed backend systems"]`},
+				})
+			},
+			expectedKept: true,
+			expectedLogo: true,
+		},
+		{
+			label: "Empty companyLogo clears the logo",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return jsonRequest(t, http.MethodPut, "/api/v1/work", map[string]any{
+					"personalWebsiteType": "Work",
+					"sortValue":           "2019-06-11",
+					"jobTitle":            "Renamed",
+					"companyLogo":         "",
+					"jobDescription":      []string{"Developed backend systems"},
+				})
+			},
+		},
+		{
+			label: "Uploaded file replaces the logo",
+			request: func(t *testing.T) events.APIGatewayProxyRequest {
+				return multipartRequest(t, [][2]string{
+					{"personalWebsiteType", "Work"},
+					{"sortValue", "2019-06-11"},
+					{"jobTitle", "Renamed"},
+					{"jobDescription", `["Developed backend systems"]`},
+				}, models.FileData{FieldName: "companyLogo", Filename: "logo.png", Content: testPNG(t, 8, 8, color.White)})
+			},
+			expectedLogo: true,
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			fake.objects[existingLogoKey] = fakeS3Object{content: []byte("png!"), contentType: "image/png"}
+			if _, err := database.PostWork(context.Background(), s.DB.Client, s.TableName, models.Work{
+				PersonalWebsiteType: "Work",
+				SortValue:           "2019-06-11",
+				JobTitle:            "Software Engineer",
+				CompanyLogoRef:      s.S3.MediaRef(existingLogoKey, "image/png", 4),
+				JobDescription:      []string{"Developed backend systems"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := s.updateWorkHandler(context.Background(), test.request(t))
+			if err != nil || res.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d %s: %v", res.StatusCode, res.Body, err)
+			}
+
+			var work models.Work
+			if err := database.GetItem(context.Background(), s.DB.Client, s.TableName, "Work", "2019-06-11", &work); err != nil {
+				t.Fatal(err)
+			}
+			if work.JobTitle != "Renamed" {
+				t.Errorf("expected jobTitle Renamed, got %q", work.JobTitle)
+			}
+			ref := work.GetCompanyLogoRef()
+			if (ref != nil) != test.expectedLogo {
+				t.Fatalf("expected logo %v, got %+v", test.expectedLogo, ref)
+			}
+			if ref != nil && (ref.Key == existingLogoKey) != test.expectedKept {
+				t.Errorf("expected the existing logo to be kept %v, got %s", test.expectedKept, ref.Key)
+			}
+			if _, ok := fake.objects[existingLogoKey]; ok != test.expectedKept {
+				t.Errorf("expected the existing logo object to be kept %v, got %v", test.expectedKept, ok)
+			}
+		})
+	}
+}
+
+func TestCompanyLogoOfOtherWorkIsKept(t *testing.T) {
+	for _, test := range []struct {
+		label   string
+		handler func(*Service, context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		request events.APIGatewayProxyRequest
+	}{
+		{
+			label:   "Update clearing the logo",
+			handler: (*Service).updateWorkHandler,
+			request: jsonRequest(t, http.MethodPut, "/api/v1/work", map[string]any{
+				"personalWebsiteType": "Work",
+				"sortValue":           "2020-01-01",
+				"jobTitle":            "Renamed",
+				"companyLogo":         "",
+				"jobDescription":      []string{"Developed backend systems"},
+			}),
+		},
+		{
+			label:   "Delete",
+			handler: (*Service).deleteWorkHandler,
+			request: jsonRequest(t, http.MethodDelete, "/api/v1/work", map[string]any{
+				"personalWebsiteType": "Work",
+				"sortValue":           "2020-01-01",
+				"jobTitle":            "Software Engineer",
+				"jobDescription":      []string{"Developed backend systems"},
+			}),
+		},
+	} {
+		t.Run(test.label, func(t *testing.T) {
+			s, fake := newTestService(t)
+			fake.objects[existingLogoKey] = fakeS3Object{content: []byte("png!"), contentType: "image/png"}
+			// the logo of the other work was set to a URL of the logo of 2019-06-11
+			if _, err := database.PostWork(context.Background(), s.DB.Client, s.TableName, models.Work{
+				PersonalWebsiteType: "Work",
+				SortValue:           "2020-01-01",
+				JobTitle:            "Software Engineer",
+				CompanyLogoRef:      s.S3.MediaRef(existingLogoKey, "image/png", 4),
+				JobDescription:      []string{"Developed backend systems"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := test.handler(s, context.Background(), test.request)
+			if err != nil || res.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d %s: %v", res.StatusCode, res.Body, err)
+			}
+			if _, ok := fake.objects[existingLogoKey]; !ok {
+				t.Errorf("expected the logo of 2019-06-11 to be kept")
+			}
+		})
+	}
+}
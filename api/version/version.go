@@ -0,0 +1,53 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X github.com/thomasmendez/personal-website-backend/api/version.Commit=<commit>"
+// See the build-go target in the Makefile
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	Env       string `json:"env"`
+}
+
+// Get returns the build metadata of the running binary. When the commit was
+// not set through ldflags it falls back to the VCS information embedded by the
+// go toolchain, if any.
+func Get(env string) Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Env:       env,
+	}
+
+	if info.Commit == "unknown" {
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range buildInfo.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildTime == "unknown" {
+						info.BuildTime = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}